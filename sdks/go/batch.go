@@ -0,0 +1,124 @@
+package main
+
+import (
+	"errors"
+	"fmt"
+	"net/http"
+	"time"
+
+	"github.com/gin-gonic/gin"
+)
+
+// BatchTransformRequest mirrors TransformRequest, but carries many events
+// to run the same beforeSend code against in one call.
+type BatchTransformRequest struct {
+	BeforeSendCode string                   `json:"beforeSendCode" binding:"required"`
+	Events         []map[string]interface{} `json:"events" binding:"required"`
+	// SDK selects which language BeforeSendCode is written in; defaults to
+	// "go". A "/:sdk/transform/batch" path prefix overrides this field when
+	// both are present.
+	SDK string `json:"sdk,omitempty"`
+}
+
+// BatchTransformResult is one event's outcome within a batch - partial
+// failures (a panic on event 7 of 100) don't fail the whole request, so
+// each item carries its own success/error state.
+type BatchTransformResult struct {
+	Index            int         `json:"index"`
+	Success          bool        `json:"success"`
+	TransformedEvent interface{} `json:"transformedEvent,omitempty"`
+	Diff             []DiffOp    `json:"diff,omitempty"`
+	Error            string      `json:"error,omitempty"`
+}
+
+type BatchTransformResponse struct {
+	Results []BatchTransformResult `json:"results"`
+}
+
+func batchTransformHandler(c *gin.Context) {
+	var req BatchTransformRequest
+
+	if err := c.ShouldBindJSON(&req); err != nil {
+		c.JSON(http.StatusBadRequest, gin.H{"error": "Missing beforeSendCode or events"})
+		return
+	}
+
+	sdk, executor, ok := executorFor(c, req.SDK)
+	if !ok {
+		c.JSON(http.StatusBadRequest, gin.H{"error": fmt.Sprintf("unsupported sdk %q", sdk)})
+		return
+	}
+
+	start := time.Now()
+
+	var results []*ExecutionResult
+	var errs []error
+	if batchExecutor, ok := executor.(BatchExecutor); ok {
+		results, errs = batchExecutor.TransformBatch(req.Events, req.BeforeSendCode)
+	} else {
+		results = make([]*ExecutionResult, len(req.Events))
+		errs = make([]error, len(req.Events))
+		for i, event := range req.Events {
+			results[i], errs[i] = executor.Transform(event, req.BeforeSendCode)
+		}
+	}
+
+	// TransformBatch/Transform don't expose a per-item clock, so every
+	// item's observeTransform call shares the batch's overall start time;
+	// the duration histogram this feeds ends up counting batch wall time
+	// once per item rather than isolating each one; the per-sdk/outcome
+	// counters and result-size histogram it also updates are exact.
+	response := BatchTransformResponse{Results: make([]BatchTransformResult, len(req.Events))}
+	for i, event := range req.Events {
+		if err := errs[i]; err != nil {
+			observeTransform(sdk, batchOutcomeFor(err), start, nil)
+			response.Results[i] = BatchTransformResult{Index: i, Success: false, Error: batchErrorMessage(err)}
+			continue
+		}
+		observeTransform(sdk, outcomeFor(results[i].Value), start, results[i].Value)
+		response.Results[i] = BatchTransformResult{
+			Index:            i,
+			Success:          true,
+			TransformedEvent: results[i].Value,
+			Diff:             diffEvent(event, results[i].Value),
+		}
+	}
+
+	c.JSON(http.StatusOK, response)
+}
+
+// batchOutcomeFor labels a failed batch item's metrics outcome the same way
+// writeTransformError labels a failed single-transform request.
+func batchOutcomeFor(err error) string {
+	var compileErr *CompileError
+	var timeoutErr *TimeoutError
+	switch {
+	case errors.As(err, &compileErr):
+		return "compile_error"
+	case errors.As(err, &timeoutErr):
+		return "timeout"
+	default:
+		return "runtime_error"
+	}
+}
+
+// batchErrorMessage unwraps a typed executor error to the same message
+// text transformHandler would have surfaced for a single-event request.
+func batchErrorMessage(err error) string {
+	var compileErr *CompileError
+	var runtimeErr *RuntimeError
+	var timeoutErr *TimeoutError
+	var tooLargeErr *ResultTooLargeError
+	switch {
+	case errors.As(err, &compileErr):
+		return compileErr.Message
+	case errors.As(err, &timeoutErr):
+		return timeoutErr.Message
+	case errors.As(err, &tooLargeErr):
+		return tooLargeErr.Message
+	case errors.As(err, &runtimeErr):
+		return "Transformation error: " + runtimeErr.Message
+	default:
+		return err.Error()
+	}
+}
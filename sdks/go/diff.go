@@ -0,0 +1,109 @@
+package main
+
+import (
+	"encoding/json"
+	"reflect"
+	"sort"
+	"strings"
+)
+
+// DiffOp is one RFC 6902-style JSON Patch operation describing a change
+// beforeSend made between the input event and the transformed one.
+type DiffOp struct {
+	Op    string      `json:"op"`
+	Path  string      `json:"path"`
+	Value interface{} `json:"value,omitempty"`
+}
+
+// diffEvent computes the diff between an input event and a transform's
+// result, if that result is itself an event (tracesSampler's float return
+// has nothing to diff against).
+func diffEvent(before map[string]interface{}, after interface{}) []DiffOp {
+	afterMap, ok := asStringMap(after)
+	if !ok {
+		return nil
+	}
+	return computeDiff(before, afterMap)
+}
+
+// asStringMap returns v as a map[string]interface{} if it is one - or, via
+// reflection, if it's a map type with string keys whose underlying type is
+// map[string]interface{} (e.g. the interp engine's named Event type), which
+// a plain type assertion doesn't see through.
+func asStringMap(v interface{}) (map[string]interface{}, bool) {
+	if m, ok := v.(map[string]interface{}); ok {
+		return m, true
+	}
+	rv := reflect.ValueOf(v)
+	if !rv.IsValid() || rv.Kind() != reflect.Map || rv.Type().Key().Kind() != reflect.String {
+		return nil, false
+	}
+	m := make(map[string]interface{}, rv.Len())
+	for _, key := range rv.MapKeys() {
+		m[key.String()] = rv.MapIndex(key).Interface()
+	}
+	return m, true
+}
+
+// computeDiff walks before and after recursively, emitting "add", "remove",
+// and "replace" ops for every path that differs. It's a best-effort diff
+// for arbitrary JSON-like structures, not a general JSON Patch producer -
+// it doesn't attempt to minimize array diffs with moves or copies.
+func computeDiff(before, after map[string]interface{}) []DiffOp {
+	var ops []DiffOp
+	diffMaps("", before, after, &ops)
+	sort.Slice(ops, func(i, j int) bool { return ops[i].Path < ops[j].Path })
+	return ops
+}
+
+func diffMaps(path string, before, after map[string]interface{}, ops *[]DiffOp) {
+	for key, beforeVal := range before {
+		childPath := path + "/" + escapeJSONPointerToken(key)
+		afterVal, ok := after[key]
+		if !ok {
+			*ops = append(*ops, DiffOp{Op: "remove", Path: childPath})
+			continue
+		}
+		diffValue(childPath, beforeVal, afterVal, ops)
+	}
+	for key, afterVal := range after {
+		if _, ok := before[key]; ok {
+			continue
+		}
+		*ops = append(*ops, DiffOp{Op: "add", Path: path + "/" + escapeJSONPointerToken(key), Value: afterVal})
+	}
+}
+
+// escapeJSONPointerToken escapes a map key for use as one segment of an RFC
+// 6901 JSON Pointer, as RFC 6902 patch paths require: "~" becomes "~0" and
+// "/" becomes "~1", in that order, so a literal key like "a/b" doesn't get
+// mistaken for a path into a nested "a" object.
+func escapeJSONPointerToken(token string) string {
+	token = strings.ReplaceAll(token, "~", "~0")
+	token = strings.ReplaceAll(token, "/", "~1")
+	return token
+}
+
+func diffValue(path string, before, after interface{}, ops *[]DiffOp) {
+	beforeMap, beforeIsMap := before.(map[string]interface{})
+	afterMap, afterIsMap := after.(map[string]interface{})
+	if beforeIsMap && afterIsMap {
+		diffMaps(path, beforeMap, afterMap, ops)
+		return
+	}
+
+	if !jsonEqual(before, after) {
+		*ops = append(*ops, DiffOp{Op: "replace", Path: path, Value: after})
+	}
+}
+
+// jsonEqual compares two JSON-like values by their marshaled form, so map
+// key order and concrete numeric types don't cause false positives.
+func jsonEqual(a, b interface{}) bool {
+	aJSON, errA := json.Marshal(a)
+	bJSON, errB := json.Marshal(b)
+	if errA != nil || errB != nil {
+		return false
+	}
+	return string(aJSON) == string(bJSON)
+}
@@ -0,0 +1,117 @@
+package main
+
+import (
+	"sort"
+
+	"github.com/gin-gonic/gin"
+)
+
+// Executor is implemented once per supported beforeSend/tracesSampler
+// language. Validate checks code for errors without running it; Transform
+// runs it against an event and returns the resulting execution, including
+// any trace entries the code recorded along the way.
+type Executor interface {
+	Validate(code string) ValidationResponse
+	Transform(event map[string]interface{}, code string) (*ExecutionResult, error)
+}
+
+// traceSentinel separates a generated program's result output from its
+// trailing trace JSON on stdout. The go and python harnesses print it,
+// verbatim, as a line of its own between the two; the javascript and
+// interp paths run in-process and collect their trace without it.
+const traceSentinel = "@@BEFORESEND_TRACE@@"
+
+// TraceEntry is one value the user's code chose to record via the Debug
+// helper injected into its execution environment - a lightweight
+// alternative to attaching a debugger for a single beforeSend run.
+type TraceEntry struct {
+	Key   string      `json:"key"`
+	Value interface{} `json:"value"`
+}
+
+// ExecutionResult is what a successful Transform produces: the resulting
+// event (or sample rate, for tracesSampler) plus whatever trace entries
+// the code recorded.
+type ExecutionResult struct {
+	Value interface{}
+	Trace []TraceEntry
+}
+
+// CompileError means the user's code failed to compile/parse; handlers
+// map it to an HTTP 400.
+type CompileError struct {
+	Message string
+}
+
+func (e *CompileError) Error() string { return e.Message }
+
+// RuntimeError means the user's code compiled but panicked, errored, or
+// otherwise failed while running; handlers map it to an HTTP 500 and
+// surface Traceback alongside the error.
+type RuntimeError struct {
+	Message   string
+	Traceback string
+}
+
+func (e *RuntimeError) Error() string { return e.Message }
+
+// TimeoutError means the user's code ran past the sandbox's wall-clock
+// timeout and was killed; handlers map it to an HTTP 408.
+type TimeoutError struct {
+	Message string
+}
+
+func (e *TimeoutError) Error() string { return e.Message }
+
+// ResultTooLargeError means the user's code returned a value whose
+// marshaled size exceeds the sandbox's limit; handlers map it to an HTTP
+// 413. A result nested past the depth limit is a CompileError (400)
+// instead.
+type ResultTooLargeError struct {
+	Message string
+}
+
+func (e *ResultTooLargeError) Error() string { return e.Message }
+
+// BatchExecutor is implemented by executors that can run many events
+// against one compiled/parsed program without redoing that work per
+// event - worthwhile for the go SDK, whose build step dominates a single
+// transform's latency. batchTransformHandler falls back to looping
+// Transform for executors that don't implement it.
+type BatchExecutor interface {
+	TransformBatch(events []map[string]interface{}, code string) ([]*ExecutionResult, []error)
+}
+
+// executors is the registry of SDKs this service can dispatch to. /health
+// enumerates its keys so clients know what's available.
+var executors = map[string]Executor{
+	"go":         goExecutor{},
+	"python":     pythonExecutor{},
+	"javascript": jsExecutor{},
+}
+
+func availableSDKs() []string {
+	sdks := make([]string, 0, len(executors))
+	for sdk := range executors {
+		sdks = append(sdks, sdk)
+	}
+	sort.Strings(sdks)
+	return sdks
+}
+
+// executorFor resolves which SDK a request targets. In order of
+// precedence: a "/:sdk/..." path prefix, then bodySDK (the request body's
+// own "sdk" field, when the caller parsed one), then a "?sdk=" query
+// param, defaulting to "go" for backward compatibility with the original
+// Go-only endpoints.
+func executorFor(c *gin.Context, bodySDK string) (string, Executor, bool) {
+	sdk := c.Param("sdk")
+	if sdk == "" {
+		sdk = bodySDK
+	}
+	if sdk == "" {
+		sdk = c.DefaultQuery("sdk", "go")
+	}
+	executor, ok := executors[sdk]
+	return sdk, executor, ok
+}
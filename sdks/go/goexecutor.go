@@ -0,0 +1,476 @@
+package main
+
+import (
+	"bytes"
+	"context"
+	"encoding/json"
+	"fmt"
+	"io/ioutil"
+	"os"
+	"path/filepath"
+	"strconv"
+	"strings"
+)
+
+// goExecutor runs beforeSend/tracesSampler code through the full go
+// build/run toolchain: it's the original, highest-fidelity execution
+// path, and the default for the "go" SDK.
+type goExecutor struct{}
+
+func (goExecutor) Validate(code string) ValidationResponse {
+	return validateCode(code)
+}
+
+func (goExecutor) Transform(event map[string]interface{}, code string) (*ExecutionResult, error) {
+	if err := checkImportAllowlist(code); err != nil {
+		return nil, &CompileError{Message: err.Error()}
+	}
+
+	// Create temporary directory for the transform execution
+	tmpDir, err := ioutil.TempDir("", "beforesend-*")
+	if err != nil {
+		return nil, fmt.Errorf("failed to create temp directory: %w", err)
+	}
+	defer os.RemoveAll(tmpDir)
+
+	// Create the transform program
+	programPath := filepath.Join(tmpDir, "transform.go")
+	eventJSON, _ := json.Marshal(event)
+
+	// Use strconv.Quote to properly escape the JSON for Go source code
+	// This handles backticks, quotes, newlines, and all special characters
+	quotedEventJSON := strconv.Quote(string(eventJSON))
+
+	program := fmt.Sprintf(`package main
+
+import (
+	"encoding/json"
+	"fmt"
+	"math"
+	"regexp"
+	"sort"
+	"strconv"
+	"strings"
+	"time"
+)
+
+// Suppress unused import warnings for packages the sandbox allows beforeSend
+// code to reference but that this harness doesn't otherwise use.
+var (
+	_ = strings.Contains
+	_ = regexp.MustCompile
+	_ = time.Now
+	_ = strconv.Itoa
+	_ = math.Abs
+	_ = sort.Strings
+)
+
+type Event map[string]interface{}
+type EventHint map[string]interface{}
+
+type traceEntry struct {
+	Key   string      `+"`json:\"key\"`"+`
+	Value interface{} `+"`json:\"value\"`"+`
+}
+
+var traceEntries []traceEntry
+
+// Debug records a key/value pair visible in the transform's trace output,
+// without affecting the event the function returns.
+func Debug(key string, value interface{}) {
+	traceEntries = append(traceEntries, traceEntry{Key: key, Value: value})
+}
+
+func main() {
+	eventJSON := %s
+
+	var event Event
+	if err := json.Unmarshal([]byte(eventJSON), &event); err != nil {
+		panic(err)
+	}
+
+	// User's beforeSend/tracesSampler code
+	// Returns interface{} to support both Event (map) and float64 (sample rate)
+	result := func(event Event, hint EventHint) interface{} {
+		%s
+	}(event, EventHint{})
+
+	printResult(result)
+
+	fmt.Println(%s)
+	traceJSON, err := json.Marshal(traceEntries)
+	if err != nil {
+		panic(err)
+	}
+	fmt.Println(string(traceJSON))
+}
+
+func printResult(result interface{}) {
+	if result == nil {
+		fmt.Println("null")
+		return
+	}
+
+	switch v := result.(type) {
+	case float64:
+		// tracesSampler returns a float
+		fmt.Printf("%%v\n", v)
+	case int:
+		// Integer (convert to float for consistency)
+		fmt.Printf("%%v\n", float64(v))
+	default:
+		// beforeSend returns an event (or some other JSON-marshalable value)
+		output, err := json.Marshal(v)
+		if err != nil {
+			panic(err)
+		}
+		fmt.Println(string(output))
+	}
+}
+`, quotedEventJSON, code, strconv.Quote(traceSentinel))
+
+	// Write the program to file
+	if err := ioutil.WriteFile(programPath, []byte(program), 0644); err != nil {
+		return nil, fmt.Errorf("failed to write program: %w", err)
+	}
+
+	// Initialize go module in temp directory; the harness uses only the
+	// standard library, so the module has no requirements
+	goModContent := `module transform
+go 1.22
+`
+	goModPath := filepath.Join(tmpDir, "go.mod")
+	if err := ioutil.WriteFile(goModPath, []byte(goModContent), 0644); err != nil {
+		return nil, fmt.Errorf("failed to write go.mod: %w", err)
+	}
+
+	// go mod tidy and go build run the toolchain, not user code - budget
+	// them against buildTimeout, which has room for a cold module/build
+	// cache, rather than sandboxTimeout (see sandboxedBuildCommand).
+	buildCtx, buildCancel := context.WithTimeout(context.Background(), buildTimeout)
+	defer buildCancel()
+
+	// Run go mod tidy to create go.sum
+	tidyCmd := sandboxedBuildCommand(buildCtx, tmpDir, "mod", "tidy")
+	var tidyErr bytes.Buffer
+	tidyCmd.Stderr = &tidyErr
+	if err := tidyCmd.Run(); err != nil {
+		if buildCtx.Err() == context.DeadlineExceeded || cpuLimitKilled(err) {
+			return nil, &TimeoutError{Message: fmt.Sprintf("dependency resolution timed out after %s", buildTimeout)}
+		}
+		return nil, &CompileError{Message: fmt.Sprintf("failed to resolve dependencies: %s", tidyErr.String())}
+	}
+
+	// Try to compile first to catch syntax errors
+	compileCmd := sandboxedBuildCommand(buildCtx, tmpDir, "build", "-mod=readonly", "-o", "/dev/null", "transform.go")
+	var compileErr bytes.Buffer
+	compileCmd.Stderr = &compileErr
+
+	if err := compileCmd.Run(); err != nil {
+		if buildCtx.Err() == context.DeadlineExceeded || cpuLimitKilled(err) {
+			return nil, &TimeoutError{Message: fmt.Sprintf("compilation timed out after %s", buildTimeout)}
+		}
+		return nil, &CompileError{Message: fmt.Sprintf("failed to compile beforeSend code: %s", compileErr.String())}
+	}
+
+	// Execute the program, under its own, separate (and much tighter)
+	// execution-timeout budget.
+	runCtx, runCancel := context.WithTimeout(context.Background(), sandboxTimeout)
+	defer runCancel()
+
+	runCmd := sandboxedCommand(runCtx, tmpDir, "run", "transform.go")
+	var stdout, stderr bytes.Buffer
+	runCmd.Stdout = &stdout
+	runCmd.Stderr = &stderr
+
+	if err := runCmd.Run(); err != nil {
+		errorMsg := stderr.String()
+		if errorMsg == "" {
+			errorMsg = err.Error()
+		}
+		if runCtx.Err() == context.DeadlineExceeded || cpuLimitKilled(err) {
+			return nil, &TimeoutError{Message: fmt.Sprintf("execution timed out after %s", sandboxTimeout)}
+		}
+		return nil, &RuntimeError{Message: errorMsg, Traceback: errorMsg}
+	}
+
+	// The program's output is the result line(s) followed by a sentinel
+	// and the trace JSON; split those apart before parsing either.
+	resultPart, tracePart := splitTraceOutput(stdout.String())
+
+	trace, err := parseTrace(tracePart)
+	if err != nil {
+		return nil, &RuntimeError{Message: err.Error()}
+	}
+
+	if resultPart == "null" {
+		return &ExecutionResult{Value: nil, Trace: trace}, nil
+	}
+
+	// Try to parse as a number first (for tracesSampler)
+	if num, err := strconv.ParseFloat(resultPart, 64); err == nil {
+		return &ExecutionResult{Value: num, Trace: trace}, nil
+	}
+
+	// Otherwise parse as arbitrary JSON (an event, or any other
+	// JSON-marshalable value the code chose to return)
+	var transformedEvent interface{}
+	if err := json.Unmarshal([]byte(resultPart), &transformedEvent); err != nil {
+		return nil, &RuntimeError{Message: fmt.Sprintf("failed to parse result: %v", err)}
+	}
+
+	if err := enforceResultLimits(transformedEvent); err != nil {
+		return nil, err
+	}
+
+	return &ExecutionResult{Value: transformedEvent, Trace: trace}, nil
+}
+
+// batchHarness is like the program Transform generates, except it reads
+// its event from os.Args[1] instead of embedding one literal event JSON -
+// so it can be compiled once and run again for every event in a batch.
+const batchHarness = `package main
+
+import (
+	"encoding/json"
+	"fmt"
+	"math"
+	"os"
+	"regexp"
+	"sort"
+	"strconv"
+	"strings"
+	"time"
+)
+
+// Suppress unused import warnings for packages the sandbox allows beforeSend
+// code to reference but that this harness doesn't otherwise use.
+var (
+	_ = strings.Contains
+	_ = regexp.MustCompile
+	_ = time.Now
+	_ = strconv.Itoa
+	_ = math.Abs
+	_ = sort.Strings
+)
+
+type Event map[string]interface{}
+type EventHint map[string]interface{}
+
+type traceEntry struct {
+	Key   string      ` + "`json:\"key\"`" + `
+	Value interface{} ` + "`json:\"value\"`" + `
+}
+
+var traceEntries []traceEntry
+
+// Debug records a key/value pair visible in the transform's trace output,
+// without affecting the event the function returns.
+func Debug(key string, value interface{}) {
+	traceEntries = append(traceEntries, traceEntry{Key: key, Value: value})
+}
+
+func main() {
+	var event Event
+	if err := json.Unmarshal([]byte(os.Args[1]), &event); err != nil {
+		panic(err)
+	}
+
+	result := func(event Event, hint EventHint) interface{} {
+%s
+	}(event, EventHint{})
+
+	printResult(result)
+
+	fmt.Println(%s)
+	traceJSON, err := json.Marshal(traceEntries)
+	if err != nil {
+		panic(err)
+	}
+	fmt.Println(string(traceJSON))
+}
+
+func printResult(result interface{}) {
+	if result == nil {
+		fmt.Println("null")
+		return
+	}
+
+	switch v := result.(type) {
+	case float64:
+		fmt.Printf("%%v\n", v)
+	case int:
+		fmt.Printf("%%v\n", float64(v))
+	default:
+		output, err := json.Marshal(v)
+		if err != nil {
+			panic(err)
+		}
+		fmt.Println(string(output))
+	}
+}
+`
+
+// TransformBatch compiles code once, then runs the resulting binary once
+// per event - avoiding the go toolchain's build overhead on every item in
+// a batch, which dominates a single transform's latency far more than
+// actually running the code does.
+func (goExecutor) TransformBatch(events []map[string]interface{}, code string) ([]*ExecutionResult, []error) {
+	results := make([]*ExecutionResult, len(events))
+	errs := make([]error, len(events))
+
+	if err := checkImportAllowlist(code); err != nil {
+		for i := range events {
+			errs[i] = &CompileError{Message: err.Error()}
+		}
+		return results, errs
+	}
+
+	tmpDir, err := ioutil.TempDir("", "beforesend-batch-*")
+	if err != nil {
+		for i := range events {
+			errs[i] = fmt.Errorf("failed to create temp directory: %w", err)
+		}
+		return results, errs
+	}
+	defer os.RemoveAll(tmpDir)
+
+	programPath := filepath.Join(tmpDir, "transform.go")
+	program := fmt.Sprintf(batchHarness, code, strconv.Quote(traceSentinel))
+	if err := ioutil.WriteFile(programPath, []byte(program), 0644); err != nil {
+		for i := range events {
+			errs[i] = fmt.Errorf("failed to write program: %w", err)
+		}
+		return results, errs
+	}
+
+	goModContent := `module transform
+go 1.22
+`
+	if err := ioutil.WriteFile(filepath.Join(tmpDir, "go.mod"), []byte(goModContent), 0644); err != nil {
+		for i := range events {
+			errs[i] = fmt.Errorf("failed to write go.mod: %w", err)
+		}
+		return results, errs
+	}
+
+	// See the matching comment in Transform: the build step gets its own,
+	// larger budget separate from the per-event execution timeout below.
+	buildCtx, cancel := context.WithTimeout(context.Background(), buildTimeout)
+	defer cancel()
+
+	tidyCmd := sandboxedBuildCommand(buildCtx, tmpDir, "mod", "tidy")
+	var tidyErr bytes.Buffer
+	tidyCmd.Stderr = &tidyErr
+	if err := tidyCmd.Run(); err != nil {
+		msg := fmt.Sprintf("failed to resolve dependencies: %s", tidyErr.String())
+		var batchErr error = &CompileError{Message: msg}
+		if buildCtx.Err() == context.DeadlineExceeded || cpuLimitKilled(err) {
+			batchErr = &TimeoutError{Message: fmt.Sprintf("dependency resolution timed out after %s", buildTimeout)}
+		}
+		for i := range events {
+			errs[i] = batchErr
+		}
+		return results, errs
+	}
+
+	binaryPath := filepath.Join(tmpDir, "transform")
+	buildCmd := sandboxedBuildCommand(buildCtx, tmpDir, "build", "-mod=readonly", "-o", binaryPath, "transform.go")
+	var compileErr bytes.Buffer
+	buildCmd.Stderr = &compileErr
+	if err := buildCmd.Run(); err != nil {
+		msg := fmt.Sprintf("failed to compile beforeSend code: %s", compileErr.String())
+		var batchErr error = &CompileError{Message: msg}
+		if buildCtx.Err() == context.DeadlineExceeded || cpuLimitKilled(err) {
+			batchErr = &TimeoutError{Message: fmt.Sprintf("compilation timed out after %s", buildTimeout)}
+		}
+		for i := range events {
+			errs[i] = batchErr
+		}
+		return results, errs
+	}
+
+	for i, event := range events {
+		results[i], errs[i] = runCompiledTransform(binaryPath, event)
+	}
+	return results, errs
+}
+
+// runCompiledTransform runs a transform binary built by TransformBatch
+// against a single event, giving it its own fresh timeout budget rather
+// than sharing one across the whole batch.
+func runCompiledTransform(binaryPath string, event map[string]interface{}) (*ExecutionResult, error) {
+	eventJSON, err := json.Marshal(event)
+	if err != nil {
+		return nil, fmt.Errorf("failed to marshal event: %w", err)
+	}
+
+	ctx, cancel := context.WithTimeout(context.Background(), sandboxTimeout)
+	defer cancel()
+
+	cmd := sandboxedInterpreterCommand(ctx, binaryPath, string(eventJSON))
+	var stdout, stderr bytes.Buffer
+	cmd.Stdout = &stdout
+	cmd.Stderr = &stderr
+
+	if err := cmd.Run(); err != nil {
+		errorMsg := stderr.String()
+		if errorMsg == "" {
+			errorMsg = err.Error()
+		}
+		if ctx.Err() == context.DeadlineExceeded || cpuLimitKilled(err) {
+			return nil, &TimeoutError{Message: fmt.Sprintf("execution timed out after %s", sandboxTimeout)}
+		}
+		return nil, &RuntimeError{Message: errorMsg, Traceback: errorMsg}
+	}
+
+	resultPart, tracePart := splitTraceOutput(stdout.String())
+
+	trace, err := parseTrace(tracePart)
+	if err != nil {
+		return nil, &RuntimeError{Message: err.Error()}
+	}
+
+	if resultPart == "null" {
+		return &ExecutionResult{Value: nil, Trace: trace}, nil
+	}
+
+	if num, err := strconv.ParseFloat(resultPart, 64); err == nil {
+		return &ExecutionResult{Value: num, Trace: trace}, nil
+	}
+
+	var transformedEvent interface{}
+	if err := json.Unmarshal([]byte(resultPart), &transformedEvent); err != nil {
+		return nil, &RuntimeError{Message: fmt.Sprintf("failed to parse result: %v", err)}
+	}
+
+	if err := enforceResultLimits(transformedEvent); err != nil {
+		return nil, err
+	}
+
+	return &ExecutionResult{Value: transformedEvent, Trace: trace}, nil
+}
+
+// splitTraceOutput separates a generated Go program's result line from the
+// trace JSON trailing traceSentinel.
+func splitTraceOutput(stdout string) (result, trace string) {
+	parts := strings.SplitN(stdout, traceSentinel, 2)
+	result = strings.TrimSpace(parts[0])
+	if len(parts) == 2 {
+		trace = strings.TrimSpace(parts[1])
+	}
+	return result, trace
+}
+
+// parseTrace decodes the JSON array of {key, value} entries recorded via
+// Debug(). An empty or "null" trace (no Debug calls) is not an error.
+func parseTrace(traceJSON string) ([]TraceEntry, error) {
+	if traceJSON == "" || traceJSON == "null" {
+		return nil, nil
+	}
+	var trace []TraceEntry
+	if err := json.Unmarshal([]byte(traceJSON), &trace); err != nil {
+		return nil, fmt.Errorf("failed to parse trace: %w", err)
+	}
+	return trace, nil
+}
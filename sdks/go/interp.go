@@ -0,0 +1,160 @@
+package main
+
+import (
+	"context"
+	"encoding/json"
+	"fmt"
+	"os"
+	"reflect"
+	"strconv"
+
+	"github.com/traefik/yaegi/interp"
+	"github.com/traefik/yaegi/stdlib"
+)
+
+// Event and EventHint mirror the types the compiled-toolchain path declares
+// in its generated program. They're exported into the yaegi interpreter so
+// beforeSend code can reference them without the interpreter having to
+// re-derive the types from interpreted source.
+type Event map[string]interface{}
+type EventHint map[string]interface{}
+
+// playgroundSymbols exposes the host-defined Event/EventHint types to
+// interpreted code under the virtual "playground" package path.
+var playgroundSymbols = interp.Exports{
+	"playground/playground": {
+		"Event":     reflect.ValueOf(Event{}),
+		"EventHint": reflect.ValueOf(EventHint{}),
+	},
+}
+
+// interpResult carries evalInterp's outcome across the goroutine boundary
+// runInterp uses to bound it.
+type interpResult struct {
+	value interface{}
+	err   error
+}
+
+// maxInterpConcurrency bounds how many interp evaluations may run at once.
+// A goroutine running beforeSend code that never returns (e.g. `for {}`)
+// cannot be force-killed - Go has no API to cancel a running goroutine, and
+// there's no subprocess here for the sandbox's rlimits to reap - so
+// runInterp giving up on *waiting* for it past sandboxTimeout doesn't stop
+// it from permanently pinning a CPU core. This cap turns that into a bounded
+// number of permanently-stuck workers instead of an unbounded one.
+// Overridable via PLAYGROUND_MAX_INTERP_CONCURRENCY.
+var maxInterpConcurrency = 4
+
+// interpSlots is the semaphore maxInterpConcurrency sizes: one token is
+// held for the lifetime of each in-flight evalInterp call, including ones
+// runInterp has already given up waiting on.
+var interpSlots chan struct{}
+
+func init() {
+	if v := os.Getenv("PLAYGROUND_MAX_INTERP_CONCURRENCY"); v != "" {
+		if n, err := strconv.Atoi(v); err == nil && n > 0 {
+			maxInterpConcurrency = n
+		}
+	}
+	interpSlots = make(chan struct{}, maxInterpConcurrency)
+}
+
+// runInterp evaluates beforeSendCode as the body of
+// func(event Event, hint EventHint) interface{} using an in-process Go
+// interpreter (yaegi). Unlike the compiled-toolchain path, this never
+// shells out, writes temp files, or waits on `go mod tidy` - at the cost of
+// reduced stdlib fidelity (no cgo, limited reflection, no unsafe).
+//
+// It also has no subprocess for the sandbox's rlimits to apply to, so it
+// can only bound its own *wait*, not the evaluation itself: evalInterp runs
+// in its own goroutine, and runInterp stops waiting on it after
+// sandboxTimeout, but code that never returns keeps running - and keeps
+// occupying one of interpSlots' limited concurrency slots - indefinitely.
+// A successful result still passes through enforceResultLimits before it's
+// handed back.
+func runInterp(event map[string]interface{}, code string) (interface{}, error) {
+	ctx, cancel := context.WithTimeout(context.Background(), sandboxTimeout)
+	defer cancel()
+
+	select {
+	case interpSlots <- struct{}{}:
+	case <-ctx.Done():
+		return nil, &TimeoutError{Message: fmt.Sprintf("timed out after %s waiting for an available interp evaluation slot", sandboxTimeout)}
+	}
+
+	done := make(chan interpResult, 1)
+	go func() {
+		defer func() { <-interpSlots }()
+		value, err := evalInterp(event, code)
+		done <- interpResult{value: value, err: err}
+	}()
+
+	select {
+	case <-ctx.Done():
+		return nil, &TimeoutError{Message: fmt.Sprintf("execution timed out after %s", sandboxTimeout)}
+	case res := <-done:
+		if res.err != nil {
+			return nil, res.err
+		}
+		if err := enforceResultLimits(res.value); err != nil {
+			return nil, err
+		}
+		return res.value, nil
+	}
+}
+
+// evalInterp does the actual yaegi compile-and-call. It recovers from a
+// panic in the interpreted code so one bad snippet can't take the whole
+// process down with it - the compiled-toolchain path gets this for free
+// from running in its own subprocess.
+func evalInterp(event map[string]interface{}, code string) (result interface{}, err error) {
+	defer func() {
+		if r := recover(); r != nil {
+			result, err = nil, &RuntimeError{Message: fmt.Sprintf("panic: %v", r)}
+		}
+	}()
+
+	i := interp.New(interp.Options{})
+	if err := i.Use(stdlib.Symbols); err != nil {
+		return nil, fmt.Errorf("failed to load stdlib symbols: %w", err)
+	}
+	if err := i.Use(playgroundSymbols); err != nil {
+		return nil, fmt.Errorf("failed to register playground types: %w", err)
+	}
+
+	src := fmt.Sprintf(`package main
+
+import "playground"
+
+func BeforeSend(event playground.Event, hint playground.EventHint) interface{} {
+%s
+}
+`, code)
+
+	if _, err := i.Eval(src); err != nil {
+		return nil, &CompileError{Message: fmt.Sprintf("failed to compile beforeSend code: %s", err)}
+	}
+
+	v, err := i.Eval("BeforeSend")
+	if err != nil {
+		return nil, &CompileError{Message: fmt.Sprintf("failed to resolve beforeSend function: %s", err)}
+	}
+
+	fn, ok := v.Interface().(func(Event, EventHint) interface{})
+	if !ok {
+		return nil, &CompileError{Message: "beforeSend code did not evaluate to the expected function signature"}
+	}
+
+	// Round-trip the event through JSON so it matches the Event (map) type
+	// the interpreted code expects, same as the compiled-toolchain path.
+	eventJSON, err := json.Marshal(event)
+	if err != nil {
+		return nil, fmt.Errorf("failed to marshal event: %w", err)
+	}
+	var typedEvent Event
+	if err := json.Unmarshal(eventJSON, &typedEvent); err != nil {
+		return nil, fmt.Errorf("failed to decode event: %w", err)
+	}
+
+	return fn(typedEvent, EventHint{}), nil
+}
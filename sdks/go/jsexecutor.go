@@ -0,0 +1,85 @@
+package main
+
+import (
+	"fmt"
+	"time"
+
+	"github.com/dop251/goja"
+)
+
+// jsExecutor runs beforeSend/tracesSampler code written in JavaScript
+// in-process via goja. Compile errors (syntax) and runtime errors are kept
+// distinct by using goja.Compile for the former and vm.RunProgram for the
+// latter, mirroring the compile/run split the Go SDK makes via the go
+// toolchain.
+type jsExecutor struct{}
+
+// jsHarness wraps the user's code as the body of a function called with
+// the event and an empty hint, matching the Go/Python harnesses' shape.
+const jsHarness = `(function(event, hint) {
+%s
+})`
+
+func (jsExecutor) Validate(code string) ValidationResponse {
+	src := fmt.Sprintf(jsHarness, code)
+	if _, err := goja.Compile("beforeSend.js", src, false); err != nil {
+		return ValidationResponse{
+			Valid: false,
+			Errors: []ValidationError{
+				{Message: err.Error()},
+			},
+		}
+	}
+	return ValidationResponse{Valid: true, Errors: []ValidationError{}}
+}
+
+func (jsExecutor) Transform(event map[string]interface{}, code string) (*ExecutionResult, error) {
+	src := fmt.Sprintf(jsHarness, code)
+
+	program, err := goja.Compile("beforeSend.js", src, false)
+	if err != nil {
+		return nil, &CompileError{Message: err.Error()}
+	}
+
+	vm := goja.New()
+
+	var trace []TraceEntry
+	vm.Set("debug", func(key string, value interface{}) {
+		trace = append(trace, TraceEntry{Key: key, Value: value})
+	})
+
+	// goja has no built-in wall-clock limit, so a timer interrupts the vm
+	// from another goroutine if the code runs past the sandbox timeout.
+	timer := time.AfterFunc(sandboxTimeout, func() {
+		vm.Interrupt("execution timed out after " + sandboxTimeout.String())
+	})
+	defer timer.Stop()
+
+	fnValue, err := vm.RunProgram(program)
+	if err != nil {
+		if _, ok := err.(*goja.InterruptedError); ok {
+			return nil, &TimeoutError{Message: fmt.Sprintf("execution timed out after %s", sandboxTimeout)}
+		}
+		return nil, &RuntimeError{Message: err.Error()}
+	}
+
+	fn, ok := goja.AssertFunction(fnValue)
+	if !ok {
+		return nil, &RuntimeError{Message: "beforeSend code did not evaluate to a function"}
+	}
+
+	result, err := fn(goja.Undefined(), vm.ToValue(event), vm.ToValue(map[string]interface{}{}))
+	if err != nil {
+		if _, ok := err.(*goja.InterruptedError); ok {
+			return nil, &TimeoutError{Message: fmt.Sprintf("execution timed out after %s", sandboxTimeout)}
+		}
+		return nil, &RuntimeError{Message: err.Error()}
+	}
+
+	exported := result.Export()
+	if err := enforceResultLimits(exported); err != nil {
+		return nil, err
+	}
+
+	return &ExecutionResult{Value: exported, Trace: trace}, nil
+}
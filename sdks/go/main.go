@@ -2,38 +2,67 @@ package main
 
 import (
 	"bytes"
-	"encoding/json"
+	"errors"
 	"fmt"
 	"io/ioutil"
+	"log"
 	"net/http"
 	"os"
 	"os/exec"
 	"path/filepath"
 	"strconv"
 	"strings"
+	"time"
 
 	"github.com/gin-gonic/gin"
+	"github.com/prometheus/client_golang/prometheus/promhttp"
 )
 
 type TransformRequest struct {
 	Event          map[string]interface{} `json:"event" binding:"required"`
-	BeforeSendCode string                 `json:"beforeSendCode" binding:"required"`
+	BeforeSendCode string                 `json:"beforeSendCode,omitempty"`
+	// SnippetID, if set, resolves to a saved Snippet's Code and is used in
+	// place of BeforeSendCode - an alternative to pasting code inline.
+	SnippetID string `json:"snippetId,omitempty"`
+	// SDK selects which language the code is written in ("go", "python",
+	// "javascript", ...); defaults to "go". A "/:sdk/transform" path
+	// prefix overrides this field when both are present.
+	SDK string `json:"sdk,omitempty"`
+	// Engine selects the execution backend: "compile" (default) runs the
+	// code through the full go build/run toolchain; "interp" evaluates it
+	// in-process via yaegi, trading stdlib fidelity for speed.
+	Engine string `json:"engine,omitempty"`
 }
 
 type TransformResponse struct {
-	Success          bool        `json:"success"`
-	TransformedEvent interface{} `json:"transformedEvent,omitempty"`
-	Error            string      `json:"error,omitempty"`
-	Traceback        string      `json:"traceback,omitempty"`
+	Success          bool           `json:"success"`
+	TransformedEvent interface{}    `json:"transformedEvent,omitempty"`
+	Error            string         `json:"error,omitempty"`
+	Traceback        string         `json:"traceback,omitempty"`
+	Limits           *SandboxLimits `json:"limits,omitempty"`
+	// AppliedSnippet is set when the request resolved its code via
+	// SnippetID, so clients can show which recipe actually ran.
+	AppliedSnippet *Snippet `json:"appliedSnippet,omitempty"`
+	// Diff is a JSON-patch-style list of the paths beforeSend added,
+	// removed, or replaced between the input event and TransformedEvent.
+	Diff []DiffOp `json:"diff,omitempty"`
+	// Trace captures the key/value pairs the code recorded via the Debug
+	// helper injected into its execution environment.
+	Trace []TraceEntry `json:"trace,omitempty"`
 }
 
 type HealthResponse struct {
-	Status string `json:"status"`
-	SDK    string `json:"sdk"`
+	Status string   `json:"status"`
+	SDK    string   `json:"sdk"`
+	SDKs   []string `json:"sdks"`
 }
 
 type ValidationRequest struct {
 	Code string `json:"code" binding:"required"`
+	// SDK selects which language Code is written in; defaults to "go". A
+	// "/:sdk/validate" path prefix overrides this field when both are
+	// present.
+	SDK string `json:"sdk,omitempty"`
 }
 
 type ValidationError struct {
@@ -52,8 +81,19 @@ func setupRouter() *gin.Engine {
 	router := gin.Default()
 
 	router.POST("/transform", transformHandler)
+	router.POST("/transform/batch", batchTransformHandler)
 	router.POST("/validate", validateHandler)
+	router.POST("/:sdk/transform", transformHandler)
+	router.POST("/:sdk/transform/batch", batchTransformHandler)
+	router.POST("/:sdk/validate", validateHandler)
 	router.GET("/health", healthHandler)
+	router.GET("/metrics", gin.WrapH(promhttp.Handler()))
+	router.GET("/ws", wsHandler)
+	router.GET("/snippets", listSnippetsHandler)
+	router.GET("/snippets/:id", getSnippetHandler)
+	router.POST("/snippets", createSnippetHandler)
+	router.POST("/snippets/:id/run", runSnippetHandler)
+	router.GET("/s/:id", getSnippetHandler)
 
 	return router
 }
@@ -69,189 +109,141 @@ func transformHandler(c *gin.Context) {
 		return
 	}
 
-	// Create temporary directory for the transform execution
-	tmpDir, err := ioutil.TempDir("", "beforesend-*")
-	if err != nil {
-		c.JSON(http.StatusInternalServerError, TransformResponse{
+	sdk, executor, ok := executorFor(c, req.SDK)
+	if !ok {
+		c.JSON(http.StatusBadRequest, TransformResponse{
 			Success: false,
-			Error:   fmt.Sprintf("Failed to create temp directory: %v", err),
+			Error:   fmt.Sprintf("unsupported sdk %q", sdk),
 		})
 		return
 	}
-	defer os.RemoveAll(tmpDir)
-
-	// Create the transform program
-	programPath := filepath.Join(tmpDir, "transform.go")
-	eventJSON, _ := json.Marshal(req.Event)
-
-	// Use strconv.Quote to properly escape the JSON for Go source code
-	// This handles backticks, quotes, newlines, and all special characters
-	quotedEventJSON := strconv.Quote(string(eventJSON))
-
-	program := fmt.Sprintf(`package main
-
-import (
-	"encoding/json"
-	"fmt"
-	"strings"
-)
-
-// Suppress unused import warning
-var _ = strings.Contains
-
-type Event map[string]interface{}
-type EventHint map[string]interface{}
-
-func main() {
-	eventJSON := %s
 
-	var event Event
-	if err := json.Unmarshal([]byte(eventJSON), &event); err != nil {
-		panic(err)
+	var appliedSnippet *Snippet
+	if req.SnippetID != "" {
+		snip, ok := snippetStore.Get(req.SnippetID)
+		if !ok {
+			c.JSON(http.StatusBadRequest, TransformResponse{
+				Success: false,
+				Error:   fmt.Sprintf("snippet %q not found", req.SnippetID),
+			})
+			return
+		}
+		req.BeforeSendCode = snip.Code
+		appliedSnippet = &snip
 	}
 
-	// User's beforeSend/tracesSampler code
-	// Returns interface{} to support both Event (map) and float64 (sample rate)
-	result := func(event Event, hint EventHint) interface{} {
-		%s
-	}(event, EventHint{})
-
-	if result == nil {
-		fmt.Println("null")
+	if req.BeforeSendCode == "" {
+		c.JSON(http.StatusBadRequest, TransformResponse{
+			Success: false,
+			Error:   "Missing event or beforeSendCode",
+		})
 		return
 	}
 
-	// Handle different return types
-	switch v := result.(type) {
-	case float64:
-		// tracesSampler returns a float
-		fmt.Printf("%%v\n", v)
-	case int:
-		// Integer (convert to float for consistency)
-		fmt.Printf("%%v\n", float64(v))
-	case Event, map[string]interface{}:
-		// beforeSend returns an event
-		output, err := json.Marshal(v)
-		if err != nil {
-			panic(err)
+	start := time.Now()
+
+	if req.Engine == "interp" {
+		if sdk != "go" {
+			c.JSON(http.StatusBadRequest, TransformResponse{
+				Success: false,
+				Error:   `engine "interp" is only available for the go sdk`,
+			})
+			return
 		}
-		fmt.Println(string(output))
-	default:
-		// Try to marshal as JSON (catches other map types)
-		output, err := json.Marshal(v)
+		result, err := runInterp(req.Event, req.BeforeSendCode)
 		if err != nil {
-			panic(err)
+			writeTransformError(c, sdk, start, err)
+			return
 		}
-		fmt.Println(string(output))
-	}
-}
-`, quotedEventJSON, req.BeforeSendCode)
-
-	// Write the program to file
-	if err := ioutil.WriteFile(programPath, []byte(program), 0644); err != nil {
-		c.JSON(http.StatusInternalServerError, TransformResponse{
-			Success: false,
-			Error:   fmt.Sprintf("Failed to write program: %v", err),
+		observeTransform(sdk, outcomeFor(result), start, result)
+		c.JSON(http.StatusOK, TransformResponse{
+			Success:          true,
+			TransformedEvent: result,
+			AppliedSnippet:   appliedSnippet,
+			Diff:             diffEvent(req.Event, result),
 		})
 		return
 	}
 
-	// Initialize go module in temp directory
-	// Include sentry-go in case users want to use sentry types
-	goModContent := `module transform
-go 1.22
-`
-	goModPath := filepath.Join(tmpDir, "go.mod")
-	if err := ioutil.WriteFile(goModPath, []byte(goModContent), 0644); err != nil {
-		c.JSON(http.StatusInternalServerError, TransformResponse{
-			Success: false,
-			Error:   fmt.Sprintf("Failed to write go.mod: %v", err),
-		})
-		return
-	}
+	respondWithTransform(c, sdk, executor, req.Event, req.BeforeSendCode, appliedSnippet, start)
+}
 
-	// Run go mod tidy to create go.sum
-	tidyCmd := exec.Command("go", "mod", "tidy")
-	tidyCmd.Dir = tmpDir
-	var tidyErr bytes.Buffer
-	tidyCmd.Stderr = &tidyErr
-	if err := tidyCmd.Run(); err != nil {
-		c.JSON(http.StatusBadRequest, TransformResponse{
-			Success: false,
-			Error:   fmt.Sprintf("Failed to resolve dependencies: %s", tidyErr.String()),
-		})
+// respondWithTransform runs code against event via executor and writes the
+// TransformResponse shape shared by /transform and /snippets/:id/run:
+// diff, trace, and error-to-status-code mapping all live here once so the
+// two handlers can't drift apart.
+func respondWithTransform(c *gin.Context, sdk string, executor Executor, event map[string]interface{}, code string, appliedSnippet *Snippet, start time.Time) {
+	result, err := executor.Transform(event, code)
+	if err != nil {
+		writeTransformError(c, sdk, start, err)
 		return
 	}
 
-	// Try to compile first to catch syntax errors
-	compileCmd := exec.Command("go", "build", "-mod=readonly", "-o", "/dev/null", "transform.go")
-	compileCmd.Dir = tmpDir
-	var compileErr bytes.Buffer
-	compileCmd.Stderr = &compileErr
+	observeTransform(sdk, outcomeFor(result.Value), start, result.Value)
+	c.JSON(http.StatusOK, TransformResponse{
+		Success:          true,
+		TransformedEvent: result.Value,
+		AppliedSnippet:   appliedSnippet,
+		Diff:             diffEvent(event, result.Value),
+		Trace:            result.Trace,
+	})
+}
 
-	if err := compileCmd.Run(); err != nil {
-		errorMsg := compileErr.String()
+// writeTransformError maps an executor error to the TransformResponse/status
+// code pair documented for /transform, and records the matching outcome
+// metric. Shared by the compiled-toolchain and interp paths so the two
+// can't disagree on what a given error type means to a client.
+func writeTransformError(c *gin.Context, sdk string, start time.Time, err error) {
+	var compileErr *CompileError
+	var runtimeErr *RuntimeError
+	var timeoutErr *TimeoutError
+	var tooLargeErr *ResultTooLargeError
+	switch {
+	case errors.As(err, &compileErr):
+		observeTransform(sdk, "compile_error", start, nil)
 		c.JSON(http.StatusBadRequest, TransformResponse{
 			Success: false,
-			Error:   fmt.Sprintf("Failed to compile beforeSend code: %s", errorMsg),
+			Error:   compileErr.Message,
+			Limits:  &effectiveLimits,
 		})
-		return
-	}
-
-	// Execute the program
-	runCmd := exec.Command("go", "run", "transform.go")
-	runCmd.Dir = tmpDir
-	var stdout, stderr bytes.Buffer
-	runCmd.Stdout = &stdout
-	runCmd.Stderr = &stderr
-
-	if err := runCmd.Run(); err != nil {
-		errorMsg := stderr.String()
-		if errorMsg == "" {
-			errorMsg = err.Error()
-		}
-		c.JSON(http.StatusInternalServerError, TransformResponse{
-			Success:   false,
-			Error:     "Transformation error: " + errorMsg,
-			Traceback: errorMsg,
+	case errors.As(err, &timeoutErr):
+		observeTransform(sdk, "timeout", start, nil)
+		c.JSON(http.StatusRequestTimeout, TransformResponse{
+			Success: false,
+			Error:   timeoutErr.Message,
+			Limits:  &effectiveLimits,
 		})
-		return
-	}
-
-	// Parse the result
-	output := strings.TrimSpace(stdout.String())
-
-	if output == "null" {
-		c.JSON(http.StatusOK, TransformResponse{
-			Success:          true,
-			TransformedEvent: nil,
+	case errors.As(err, &tooLargeErr):
+		observeTransform(sdk, "runtime_error", start, nil)
+		c.JSON(http.StatusRequestEntityTooLarge, TransformResponse{
+			Success: false,
+			Error:   tooLargeErr.Message,
+			Limits:  &effectiveLimits,
 		})
-		return
-	}
-
-	// Try to parse as a number first (for tracesSampler)
-	if num, err := strconv.ParseFloat(output, 64); err == nil {
-		c.JSON(http.StatusOK, TransformResponse{
-			Success:          true,
-			TransformedEvent: num,
+	case errors.As(err, &runtimeErr):
+		observeTransform(sdk, "runtime_error", start, nil)
+		c.JSON(http.StatusInternalServerError, TransformResponse{
+			Success:   false,
+			Error:     "Transformation error: " + runtimeErr.Message,
+			Traceback: runtimeErr.Traceback,
+			Limits:    &effectiveLimits,
 		})
-		return
-	}
-
-	// Otherwise parse as JSON object (for beforeSend)
-	var transformedEvent map[string]interface{}
-	if err := json.Unmarshal([]byte(output), &transformedEvent); err != nil {
+	default:
+		observeTransform(sdk, "runtime_error", start, nil)
 		c.JSON(http.StatusInternalServerError, TransformResponse{
 			Success: false,
-			Error:   fmt.Sprintf("Failed to parse result: %v", err),
+			Error:   err.Error(),
 		})
-		return
 	}
+}
 
-	c.JSON(http.StatusOK, TransformResponse{
-		Success:          true,
-		TransformedEvent: transformedEvent,
-	})
+// outcomeFor labels a successful transform's metrics: "dropped" when
+// beforeSend returned nil to discard the event, "success" otherwise.
+func outcomeFor(value interface{}) string {
+	if value == nil {
+		return "dropped"
+	}
+	return "success"
 }
 
 func validateHandler(c *gin.Context) {
@@ -267,16 +259,34 @@ func validateHandler(c *gin.Context) {
 		return
 	}
 
+	sdk, executor, ok := executorFor(c, req.SDK)
+	if !ok {
+		c.JSON(http.StatusBadRequest, ValidationResponse{
+			Valid: false,
+			Errors: []ValidationError{
+				{Message: fmt.Sprintf("unsupported sdk %q", sdk)},
+			},
+		})
+		return
+	}
+
+	c.JSON(http.StatusOK, executor.Validate(req.Code))
+}
+
+// validateCode compiles code as the body of
+// func(event Event, hint EventHint) Event to check it for syntax errors,
+// without running it. It's shared by validateHandler and wsHandler so both
+// paths agree on what counts as valid beforeSend code.
+func validateCode(code string) ValidationResponse {
 	// Create temporary directory for validation
 	tmpDir, err := ioutil.TempDir("", "validate-*")
 	if err != nil {
-		c.JSON(http.StatusInternalServerError, ValidationResponse{
+		return ValidationResponse{
 			Valid: false,
 			Errors: []ValidationError{
 				{Message: fmt.Sprintf("Validation service error: %v", err)},
 			},
-		})
-		return
+		}
 	}
 	defer os.RemoveAll(tmpDir)
 
@@ -292,17 +302,16 @@ func main() {
 		%s
 	}
 }
-`, req.Code)
+`, code)
 
 	// Write the program to file
 	if err := ioutil.WriteFile(programPath, []byte(program), 0644); err != nil {
-		c.JSON(http.StatusInternalServerError, ValidationResponse{
+		return ValidationResponse{
 			Valid: false,
 			Errors: []ValidationError{
 				{Message: fmt.Sprintf("Validation service error: %v", err)},
 			},
-		})
-		return
+		}
 	}
 
 	// Initialize go module
@@ -311,13 +320,12 @@ go 1.22
 `
 	goModPath := filepath.Join(tmpDir, "go.mod")
 	if err := ioutil.WriteFile(goModPath, []byte(goModContent), 0644); err != nil {
-		c.JSON(http.StatusInternalServerError, ValidationResponse{
+		return ValidationResponse{
 			Valid: false,
 			Errors: []ValidationError{
 				{Message: fmt.Sprintf("Validation service error: %v", err)},
 			},
-		})
-		return
+		}
 	}
 
 	// Try to compile - this checks syntax
@@ -343,7 +351,7 @@ go 1.22
 			}
 		}
 
-		c.JSON(http.StatusOK, ValidationResponse{
+		return ValidationResponse{
 			Valid: false,
 			Errors: []ValidationError{
 				{
@@ -351,24 +359,35 @@ go 1.22
 					Message: errorMsg,
 				},
 			},
-		})
-		return
+		}
 	}
 
-	c.JSON(http.StatusOK, ValidationResponse{
+	return ValidationResponse{
 		Valid:  true,
 		Errors: []ValidationError{},
-	})
+	}
 }
 
 func healthHandler(c *gin.Context) {
 	c.JSON(http.StatusOK, HealthResponse{
 		Status: "healthy",
 		SDK:    "go",
+		SDKs:   availableSDKs(),
 	})
 }
 
 func main() {
+	// SNIPPET_LIBRARY_DIR lets operators preload a curated recipe library
+	// from a directory of JSON files instead of starting with an empty,
+	// in-memory snippet store.
+	if dir := os.Getenv("SNIPPET_LIBRARY_DIR"); dir != "" {
+		store, err := newFileSnippetStore(dir)
+		if err != nil {
+			log.Fatalf("failed to load snippet library from %s: %v", dir, err)
+		}
+		snippetStore = store
+	}
+
 	router := setupRouter()
 	router.Run(":5006")
 }
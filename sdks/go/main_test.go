@@ -3,10 +3,14 @@ package main
 import (
 	"bytes"
 	"encoding/json"
+	"fmt"
 	"net/http"
 	"net/http/httptest"
 	"strings"
 	"testing"
+	"time"
+
+	"github.com/gorilla/websocket"
 )
 
 // TestTransformWithValidBeforeSend tests transformation with valid beforeSend code
@@ -66,6 +70,199 @@ func TestTransformWithValidBeforeSend(t *testing.T) {
 	}
 }
 
+// TestTransformWithInterpEngineModifiesEvent tests that the yaegi-backed
+// "interp" engine produces the same result as the compiled-toolchain path.
+func TestTransformWithInterpEngineModifiesEvent(t *testing.T) {
+	router := setupRouter()
+
+	event := map[string]interface{}{
+		"exception": map[string]interface{}{
+			"values": []map[string]interface{}{
+				{
+					"type":  "Error",
+					"value": "Original error",
+				},
+			},
+		},
+	}
+
+	beforeSendCode := `if exception, ok := event["exception"].(map[string]interface{}); ok {
+		if values, ok := exception["values"].([]interface{}); ok && len(values) > 0 {
+			if firstValue, ok := values[0].(map[string]interface{}); ok {
+				firstValue["value"] = "Modified error"
+			}
+		}
+	}
+	return event`
+
+	payload := map[string]interface{}{
+		"event":          event,
+		"beforeSendCode": beforeSendCode,
+		"engine":         "interp",
+	}
+
+	body, _ := json.Marshal(payload)
+	req, _ := http.NewRequest("POST", "/transform", bytes.NewBuffer(body))
+	req.Header.Set("Content-Type", "application/json")
+
+	w := httptest.NewRecorder()
+	router.ServeHTTP(w, req)
+
+	if w.Code != http.StatusOK {
+		t.Errorf("Expected status 200, got %d. Body: %s", w.Code, w.Body.String())
+	}
+
+	var response map[string]interface{}
+	json.Unmarshal(w.Body.Bytes(), &response)
+
+	if response["success"] != true {
+		t.Errorf("Expected success=true, got %v", response["success"])
+	}
+
+	transformedEvent := response["transformedEvent"].(map[string]interface{})
+	exception := transformedEvent["exception"].(map[string]interface{})
+	values := exception["values"].([]interface{})
+	firstValue := values[0].(map[string]interface{})
+
+	if firstValue["value"] != "Modified error" {
+		t.Errorf("Expected 'Modified error', got %v", firstValue["value"])
+	}
+}
+
+// TestTransformWithInterpEngineIncludesDiff tests that the interp engine's
+// TransformedEvent (a named Event map, not a plain map[string]interface{})
+// still produces a populated Diff, not a silently empty one.
+func TestTransformWithInterpEngineIncludesDiff(t *testing.T) {
+	router := setupRouter()
+
+	event := map[string]interface{}{"event_id": "test123"}
+	payload := map[string]interface{}{
+		"event":          event,
+		"beforeSendCode": `event["event_id"] = "modified"; return event`,
+		"engine":         "interp",
+	}
+
+	body, _ := json.Marshal(payload)
+	req, _ := http.NewRequest("POST", "/transform", bytes.NewBuffer(body))
+	req.Header.Set("Content-Type", "application/json")
+
+	w := httptest.NewRecorder()
+	router.ServeHTTP(w, req)
+
+	if w.Code != http.StatusOK {
+		t.Fatalf("Expected status 200, got %d: %s", w.Code, w.Body.String())
+	}
+
+	var response TransformResponse
+	json.Unmarshal(w.Body.Bytes(), &response)
+
+	if len(response.Diff) != 1 || response.Diff[0].Path != "/event_id" || response.Diff[0].Op != "replace" {
+		t.Errorf("Expected a single /event_id replace op, got %v", response.Diff)
+	}
+}
+
+// TestTransformWithInterpEngineInfiniteLoopTimesOut tests that the interp
+// engine enforces the same wall-clock timeout as the compiled-toolchain
+// path, since it has no subprocess for the sandbox's rlimits to bound it.
+func TestTransformWithInterpEngineInfiniteLoopTimesOut(t *testing.T) {
+	router := setupRouter()
+
+	payload := map[string]interface{}{
+		"event":          map[string]interface{}{"event_id": "test123"},
+		"beforeSendCode": "for {}",
+		"engine":         "interp",
+	}
+
+	body, _ := json.Marshal(payload)
+	req, _ := http.NewRequest("POST", "/transform", bytes.NewBuffer(body))
+	req.Header.Set("Content-Type", "application/json")
+
+	w := httptest.NewRecorder()
+	router.ServeHTTP(w, req)
+
+	if w.Code != http.StatusRequestTimeout {
+		t.Fatalf("Expected status 408, got %d: %s", w.Code, w.Body.String())
+	}
+
+	var response map[string]interface{}
+	json.Unmarshal(w.Body.Bytes(), &response)
+
+	if !strings.Contains(response["error"].(string), "timed out") {
+		t.Errorf("Expected a timeout error, got %v", response["error"])
+	}
+}
+
+// TestTransformWithInterpEngineOversizeResultReturns413 tests that the
+// interp engine rejects an oversize result with a 413, the same as the
+// compiled-toolchain path, rather than returning it unchecked.
+func TestTransformWithInterpEngineOversizeResultReturns413(t *testing.T) {
+	router := setupRouter()
+
+	payload := map[string]interface{}{
+		"event":          map[string]interface{}{"event_id": "test123"},
+		"beforeSendCode": `event["blob"] = make([]byte, 10<<20); return event`,
+		"engine":         "interp",
+	}
+
+	body, _ := json.Marshal(payload)
+	req, _ := http.NewRequest("POST", "/transform", bytes.NewBuffer(body))
+	req.Header.Set("Content-Type", "application/json")
+
+	w := httptest.NewRecorder()
+	router.ServeHTTP(w, req)
+
+	if w.Code != http.StatusRequestEntityTooLarge {
+		t.Fatalf("Expected status 413, got %d: %s", w.Code, w.Body.String())
+	}
+}
+
+// TestInterpConcurrencyCapRejectsExcessEvaluations tests that once a
+// busy-looping interp evaluation has permanently claimed the (capped-to-1,
+// for this test) interp slot, a second interp request fails waiting for a
+// slot rather than spawning an unbounded additional stuck goroutine.
+func TestInterpConcurrencyCapRejectsExcessEvaluations(t *testing.T) {
+	originalMax, originalSlots := maxInterpConcurrency, interpSlots
+	maxInterpConcurrency = 1
+	interpSlots = make(chan struct{}, maxInterpConcurrency)
+	defer func() { maxInterpConcurrency, interpSlots = originalMax, originalSlots }()
+
+	originalTimeout := sandboxTimeout
+	sandboxTimeout = 200 * time.Millisecond
+	defer func() { sandboxTimeout = originalTimeout }()
+
+	router := setupRouter()
+	busyLoopPayload, _ := json.Marshal(map[string]interface{}{
+		"event":          map[string]interface{}{"event_id": "test123"},
+		"beforeSendCode": "for {}",
+		"engine":         "interp",
+	})
+
+	req, _ := http.NewRequest("POST", "/transform", bytes.NewBuffer(busyLoopPayload))
+	req.Header.Set("Content-Type", "application/json")
+	w := httptest.NewRecorder()
+	router.ServeHTTP(w, req)
+	if w.Code != http.StatusRequestTimeout {
+		t.Fatalf("Expected the busy loop itself to time out with 408, got %d: %s", w.Code, w.Body.String())
+	}
+
+	// The slot the busy loop claimed is never released (the goroutine
+	// running it never returns), so a second interp request should fail
+	// waiting for one rather than hanging or getting a slot anyway.
+	req2, _ := http.NewRequest("POST", "/transform", bytes.NewBuffer(busyLoopPayload))
+	req2.Header.Set("Content-Type", "application/json")
+	w2 := httptest.NewRecorder()
+	router.ServeHTTP(w2, req2)
+
+	if w2.Code != http.StatusRequestTimeout {
+		t.Fatalf("Expected the second request to time out waiting for a slot with 408, got %d: %s", w2.Code, w2.Body.String())
+	}
+	var response map[string]interface{}
+	json.Unmarshal(w2.Body.Bytes(), &response)
+	if !strings.Contains(response["error"].(string), "slot") {
+		t.Errorf("Expected a slot-wait timeout error, got %v", response["error"])
+	}
+}
+
 // TestTransformReturnsNilDropsEvent tests that returning nil drops the event
 func TestTransformReturnsNilDropsEvent(t *testing.T) {
 	router := setupRouter()
@@ -673,6 +870,149 @@ func TestTracesSamplerWithIntegerReturn(t *testing.T) {
 	}
 }
 
+// TestDisallowedImportReturns400 tests that beforeSend code referencing a
+// package outside the sandbox's import allowlist is rejected before it is
+// ever compiled or run.
+func TestDisallowedImportReturns400(t *testing.T) {
+	router := setupRouter()
+
+	event := map[string]interface{}{
+		"event_id": "test123",
+	}
+
+	beforeSendCode := `os.Exit(1)
+	return event`
+
+	payload := map[string]interface{}{
+		"event":          event,
+		"beforeSendCode": beforeSendCode,
+	}
+
+	body, _ := json.Marshal(payload)
+	req, _ := http.NewRequest("POST", "/transform", bytes.NewBuffer(body))
+	req.Header.Set("Content-Type", "application/json")
+
+	w := httptest.NewRecorder()
+	router.ServeHTTP(w, req)
+
+	if w.Code != http.StatusBadRequest {
+		t.Errorf("Expected status 400, got %d", w.Code)
+	}
+
+	var response map[string]interface{}
+	json.Unmarshal(w.Body.Bytes(), &response)
+
+	if response["success"] != false {
+		t.Errorf("Expected success=false, got %v", response["success"])
+	}
+
+	if response["limits"] == nil {
+		t.Error("Expected limits to be included in a sandbox rejection response")
+	}
+}
+
+// TestAllowlistedImportsAreUsable tests that the allowlisted packages the
+// harness doesn't otherwise call (time, strconv, math, regexp, sort)
+// actually compile and run in the generated harness, not just pass
+// checkImportAllowlist.
+func TestAllowlistedImportsAreUsable(t *testing.T) {
+	router := setupRouter()
+
+	beforeSendCode := `event["ts"] = time.Now().Unix() > 0
+	event["n"], _ = strconv.Atoi("3")
+	event["abs"] = math.Abs(-1.0)
+	event["matched"] = regexp.MustCompile("^a").MatchString("abc")
+	xs := []string{"b", "a"}
+	sort.Strings(xs)
+	event["sorted"] = xs[0] == "a"
+	return event`
+
+	payload := map[string]interface{}{
+		"event":          map[string]interface{}{"event_id": "test123"},
+		"beforeSendCode": beforeSendCode,
+	}
+
+	body, _ := json.Marshal(payload)
+	req, _ := http.NewRequest("POST", "/transform", bytes.NewBuffer(body))
+	req.Header.Set("Content-Type", "application/json")
+
+	w := httptest.NewRecorder()
+	router.ServeHTTP(w, req)
+
+	if w.Code != http.StatusOK {
+		t.Fatalf("Expected status 200, got %d: %s", w.Code, w.Body.String())
+	}
+
+	var response map[string]interface{}
+	json.Unmarshal(w.Body.Bytes(), &response)
+
+	transformedEvent, ok := response["transformedEvent"].(map[string]interface{})
+	if !ok {
+		t.Fatalf("Expected transformedEvent to be a map, got %v", response["transformedEvent"])
+	}
+	for _, field := range []string{"ts", "matched", "sorted"} {
+		if transformedEvent[field] != true {
+			t.Errorf("Expected %s=true, got %v", field, transformedEvent[field])
+		}
+	}
+	if transformedEvent["n"] != float64(3) {
+		t.Errorf("Expected n=3, got %v", transformedEvent["n"])
+	}
+	if transformedEvent["abs"] != float64(1) {
+		t.Errorf("Expected abs=1, got %v", transformedEvent["abs"])
+	}
+}
+
+// TestWebSocketStreamsValidationAndResult tests that the /ws session
+// debounces an edit and streams validation, progress, and result frames
+// for it, with the transform itself run in-process.
+func TestWebSocketStreamsValidationAndResult(t *testing.T) {
+	router := setupRouter()
+	server := httptest.NewServer(router)
+	defer server.Close()
+
+	wsURL := "ws" + strings.TrimPrefix(server.URL, "http") + "/ws"
+	conn, _, err := websocket.DefaultDialer.Dial(wsURL, nil)
+	if err != nil {
+		t.Fatalf("failed to dial websocket: %v", err)
+	}
+	defer conn.Close()
+
+	msg := map[string]interface{}{
+		"beforeSendCode": `return event`,
+		"event":          map[string]interface{}{"event_id": "test123"},
+	}
+	if err := conn.WriteJSON(msg); err != nil {
+		t.Fatalf("failed to write message: %v", err)
+	}
+
+	conn.SetReadDeadline(time.Now().Add(2 * time.Second))
+
+	var validation wsFrame
+	if err := conn.ReadJSON(&validation); err != nil {
+		t.Fatalf("failed to read validation frame: %v", err)
+	}
+	if validation.Type != "validation" {
+		t.Errorf("expected validation frame first, got %v", validation.Type)
+	}
+
+	var progress wsFrame
+	if err := conn.ReadJSON(&progress); err != nil {
+		t.Fatalf("failed to read compile_progress frame: %v", err)
+	}
+	if progress.Type != "compile_progress" {
+		t.Errorf("expected compile_progress frame, got %v", progress.Type)
+	}
+
+	var result wsFrame
+	if err := conn.ReadJSON(&result); err != nil {
+		t.Fatalf("failed to read result frame: %v", err)
+	}
+	if result.Type != "result" {
+		t.Errorf("expected result frame, got %v", result.Type)
+	}
+}
+
 // TestHealthEndpoint tests the health check endpoint
 func TestHealthEndpoint(t *testing.T) {
 	router := setupRouter()
@@ -696,3 +1036,1082 @@ func TestHealthEndpoint(t *testing.T) {
 		t.Errorf("Expected sdk=go, got %v", response["sdk"])
 	}
 }
+
+// TestHealthEndpointListsAvailableSDKs tests that /health enumerates every
+// SDK registered in the executors map, not just the default "go".
+func TestHealthEndpointListsAvailableSDKs(t *testing.T) {
+	router := setupRouter()
+
+	req, _ := http.NewRequest("GET", "/health", nil)
+	w := httptest.NewRecorder()
+	router.ServeHTTP(w, req)
+
+	var response HealthResponse
+	json.Unmarshal(w.Body.Bytes(), &response)
+
+	want := availableSDKs()
+	if len(response.SDKs) != len(want) {
+		t.Fatalf("Expected %d sdks, got %v", len(want), response.SDKs)
+	}
+	for i, sdk := range want {
+		if response.SDKs[i] != sdk {
+			t.Errorf("Expected sdks[%d]=%s, got %s", i, sdk, response.SDKs[i])
+		}
+	}
+}
+
+// TestTransformDispatchesToPythonSDK tests that "/:sdk/transform" routes to
+// the Python executor and runs Python beforeSend code.
+func TestTransformDispatchesToPythonSDK(t *testing.T) {
+	router := setupRouter()
+
+	payload := map[string]interface{}{
+		"event":          map[string]interface{}{"event_id": "test123"},
+		"beforeSendCode": "    event['event_id'] = 'modified'\n    return event",
+	}
+
+	body, _ := json.Marshal(payload)
+	req, _ := http.NewRequest("POST", "/python/transform", bytes.NewBuffer(body))
+	req.Header.Set("Content-Type", "application/json")
+
+	w := httptest.NewRecorder()
+	router.ServeHTTP(w, req)
+
+	if w.Code != http.StatusOK {
+		t.Fatalf("Expected status 200, got %d: %s", w.Code, w.Body.String())
+	}
+
+	var response TransformResponse
+	json.Unmarshal(w.Body.Bytes(), &response)
+
+	transformedEvent, ok := response.TransformedEvent.(map[string]interface{})
+	if !ok {
+		t.Fatalf("Expected transformedEvent to be a map, got %v", response.TransformedEvent)
+	}
+	if transformedEvent["event_id"] != "modified" {
+		t.Errorf("Expected event_id=modified, got %v", transformedEvent["event_id"])
+	}
+}
+
+// TestTransformDispatchesToJavaScriptSDK tests that "/:sdk/transform" routes
+// to the JavaScript executor and runs JS beforeSend code in-process.
+func TestTransformDispatchesToJavaScriptSDK(t *testing.T) {
+	router := setupRouter()
+
+	payload := map[string]interface{}{
+		"event":          map[string]interface{}{"event_id": "test123"},
+		"beforeSendCode": "event.event_id = 'modified'; return event;",
+	}
+
+	body, _ := json.Marshal(payload)
+	req, _ := http.NewRequest("POST", "/javascript/transform", bytes.NewBuffer(body))
+	req.Header.Set("Content-Type", "application/json")
+
+	w := httptest.NewRecorder()
+	router.ServeHTTP(w, req)
+
+	if w.Code != http.StatusOK {
+		t.Fatalf("Expected status 200, got %d: %s", w.Code, w.Body.String())
+	}
+
+	var response TransformResponse
+	json.Unmarshal(w.Body.Bytes(), &response)
+
+	transformedEvent, ok := response.TransformedEvent.(map[string]interface{})
+	if !ok {
+		t.Fatalf("Expected transformedEvent to be a map, got %v", response.TransformedEvent)
+	}
+	if transformedEvent["event_id"] != "modified" {
+		t.Errorf("Expected event_id=modified, got %v", transformedEvent["event_id"])
+	}
+}
+
+// TestUnsupportedSDKReturns400 tests that an unknown "/:sdk/transform"
+// prefix is rejected rather than silently falling back to Go.
+func TestUnsupportedSDKReturns400(t *testing.T) {
+	router := setupRouter()
+
+	payload := map[string]interface{}{
+		"event":          map[string]interface{}{"event_id": "test123"},
+		"beforeSendCode": "return event",
+	}
+
+	body, _ := json.Marshal(payload)
+	req, _ := http.NewRequest("POST", "/ruby/transform", bytes.NewBuffer(body))
+	req.Header.Set("Content-Type", "application/json")
+
+	w := httptest.NewRecorder()
+	router.ServeHTTP(w, req)
+
+	if w.Code != http.StatusBadRequest {
+		t.Errorf("Expected status 400, got %d", w.Code)
+	}
+}
+
+// TestCreateAndFetchSnippet tests the save/list/get lifecycle of the
+// snippet library's in-memory store.
+func TestCreateAndFetchSnippet(t *testing.T) {
+	snippetStore = newInMemorySnippetStore()
+	router := setupRouter()
+
+	payload := map[string]interface{}{
+		"name":        "Drop PII",
+		"description": "Scrubs email addresses from the event user context",
+		"code":        `return event`,
+	}
+	body, _ := json.Marshal(payload)
+	req, _ := http.NewRequest("POST", "/snippets", bytes.NewBuffer(body))
+	req.Header.Set("Content-Type", "application/json")
+
+	w := httptest.NewRecorder()
+	router.ServeHTTP(w, req)
+
+	if w.Code != http.StatusCreated {
+		t.Fatalf("Expected status 201, got %d: %s", w.Code, w.Body.String())
+	}
+
+	var created struct {
+		ID  string `json:"id"`
+		URL string `json:"url"`
+	}
+	json.Unmarshal(w.Body.Bytes(), &created)
+
+	expectedID := contentSnippetID("go", "return event")
+	if created.ID != expectedID {
+		t.Errorf("Expected content-addressed id=%s, got %s", expectedID, created.ID)
+	}
+	if created.URL != "/s/"+expectedID {
+		t.Errorf("Expected url=/s/%s, got %s", expectedID, created.URL)
+	}
+
+	getReq, _ := http.NewRequest("GET", "/snippets/"+expectedID, nil)
+	getW := httptest.NewRecorder()
+	router.ServeHTTP(getW, getReq)
+
+	if getW.Code != http.StatusOK {
+		t.Fatalf("Expected status 200, got %d", getW.Code)
+	}
+
+	var fetched Snippet
+	json.Unmarshal(getW.Body.Bytes(), &fetched)
+	if fetched.SDK != "go" {
+		t.Errorf("Expected default sdk=go, got %s", fetched.SDK)
+	}
+
+	listReq, _ := http.NewRequest("GET", "/snippets", nil)
+	listW := httptest.NewRecorder()
+	router.ServeHTTP(listW, listReq)
+
+	var listResponse struct {
+		Snippets []Snippet `json:"snippets"`
+		Total    int       `json:"total"`
+	}
+	json.Unmarshal(listW.Body.Bytes(), &listResponse)
+	if len(listResponse.Snippets) != 1 || listResponse.Total != 1 {
+		t.Errorf("Expected 1 snippet in the list, got %d (total=%d)", len(listResponse.Snippets), listResponse.Total)
+	}
+}
+
+// TestGetUnknownSnippetReturns404 tests that fetching a snippet id that
+// was never saved returns 404 rather than an empty 200.
+func TestGetUnknownSnippetReturns404(t *testing.T) {
+	snippetStore = newInMemorySnippetStore()
+	router := setupRouter()
+
+	req, _ := http.NewRequest("GET", "/snippets/does-not-exist", nil)
+	w := httptest.NewRecorder()
+	router.ServeHTTP(w, req)
+
+	if w.Code != http.StatusNotFound {
+		t.Errorf("Expected status 404, got %d", w.Code)
+	}
+}
+
+// TestTransformWithSnippetIDAppliesSavedCode tests that TransformRequest
+// can resolve its code via snippetId instead of an inline beforeSendCode,
+// and that the response echoes which snippet ran.
+func TestTransformWithSnippetIDAppliesSavedCode(t *testing.T) {
+	snippetStore = newInMemorySnippetStore()
+	if err := snippetStore.Save(Snippet{
+		ID:   "tag-env",
+		Name: "Tag Environment",
+		SDK:  "go",
+		Code: `event["tagged"] = true
+	return event`,
+	}); err != nil {
+		t.Fatalf("failed to seed snippet: %v", err)
+	}
+	router := setupRouter()
+
+	payload := map[string]interface{}{
+		"event":     map[string]interface{}{"event_id": "test123"},
+		"snippetId": "tag-env",
+	}
+	body, _ := json.Marshal(payload)
+	req, _ := http.NewRequest("POST", "/transform", bytes.NewBuffer(body))
+	req.Header.Set("Content-Type", "application/json")
+
+	w := httptest.NewRecorder()
+	router.ServeHTTP(w, req)
+
+	if w.Code != http.StatusOK {
+		t.Fatalf("Expected status 200, got %d: %s", w.Code, w.Body.String())
+	}
+
+	var response TransformResponse
+	json.Unmarshal(w.Body.Bytes(), &response)
+
+	if response.AppliedSnippet == nil || response.AppliedSnippet.ID != "tag-env" {
+		t.Errorf("Expected appliedSnippet.id=tag-env, got %v", response.AppliedSnippet)
+	}
+
+	transformedEvent, ok := response.TransformedEvent.(map[string]interface{})
+	if !ok {
+		t.Fatalf("Expected transformedEvent to be a map, got %v", response.TransformedEvent)
+	}
+	if transformedEvent["tagged"] != true {
+		t.Errorf("Expected tagged=true, got %v", transformedEvent["tagged"])
+	}
+}
+
+// TestTransformWithUnknownSnippetIDReturns400 tests that an unresolved
+// snippetId is rejected rather than silently falling through to an empty
+// beforeSendCode.
+func TestTransformWithUnknownSnippetIDReturns400(t *testing.T) {
+	snippetStore = newInMemorySnippetStore()
+	router := setupRouter()
+
+	payload := map[string]interface{}{
+		"event":     map[string]interface{}{"event_id": "test123"},
+		"snippetId": "does-not-exist",
+	}
+	body, _ := json.Marshal(payload)
+	req, _ := http.NewRequest("POST", "/transform", bytes.NewBuffer(body))
+	req.Header.Set("Content-Type", "application/json")
+
+	w := httptest.NewRecorder()
+	router.ServeHTTP(w, req)
+
+	if w.Code != http.StatusBadRequest {
+		t.Errorf("Expected status 400, got %d", w.Code)
+	}
+}
+
+// TestTransformIncludesDiffOfChangedPaths tests that a successful
+// transform reports a JSON-patch-style diff between the input event and
+// the transformed one.
+func TestTransformIncludesDiffOfChangedPaths(t *testing.T) {
+	router := setupRouter()
+
+	event := map[string]interface{}{
+		"event_id": "test123",
+		"tags":     map[string]interface{}{"env": "staging"},
+	}
+	beforeSendCode := `event["tags"].(map[string]interface{})["env"] = "production"
+	event["extra_field"] = "added"
+	delete(event, "event_id")
+	return event`
+
+	payload := map[string]interface{}{
+		"event":          event,
+		"beforeSendCode": beforeSendCode,
+	}
+	body, _ := json.Marshal(payload)
+	req, _ := http.NewRequest("POST", "/transform", bytes.NewBuffer(body))
+	req.Header.Set("Content-Type", "application/json")
+
+	w := httptest.NewRecorder()
+	router.ServeHTTP(w, req)
+
+	if w.Code != http.StatusOK {
+		t.Fatalf("Expected status 200, got %d: %s", w.Code, w.Body.String())
+	}
+
+	var response TransformResponse
+	json.Unmarshal(w.Body.Bytes(), &response)
+
+	ops := make(map[string]string)
+	for _, op := range response.Diff {
+		ops[op.Path] = op.Op
+	}
+
+	if ops["/event_id"] != "remove" {
+		t.Errorf("Expected /event_id to be removed, got %v", ops["/event_id"])
+	}
+	if ops["/extra_field"] != "add" {
+		t.Errorf("Expected /extra_field to be added, got %v", ops["/extra_field"])
+	}
+	if ops["/tags/env"] != "replace" {
+		t.Errorf("Expected /tags/env to be replaced, got %v", ops["/tags/env"])
+	}
+}
+
+// TestTransformDiffEscapesJSONPointerTokens tests that a key containing "/"
+// or "~" is escaped per RFC 6901 ("~1" and "~0" respectively), so its diff
+// path doesn't get misread as pointing into a nested object.
+func TestTransformDiffEscapesJSONPointerTokens(t *testing.T) {
+	router := setupRouter()
+
+	event := map[string]interface{}{
+		"tags": map[string]interface{}{"a/b": "one", "c~d": "two"},
+	}
+	beforeSendCode := `tags := event["tags"].(map[string]interface{})
+	tags["a/b"] = "changed"
+	tags["c~d"] = "changed"
+	return event`
+
+	payload := map[string]interface{}{
+		"event":          event,
+		"beforeSendCode": beforeSendCode,
+	}
+	body, _ := json.Marshal(payload)
+	req, _ := http.NewRequest("POST", "/transform", bytes.NewBuffer(body))
+	req.Header.Set("Content-Type", "application/json")
+
+	w := httptest.NewRecorder()
+	router.ServeHTTP(w, req)
+
+	if w.Code != http.StatusOK {
+		t.Fatalf("Expected status 200, got %d: %s", w.Code, w.Body.String())
+	}
+
+	var response TransformResponse
+	json.Unmarshal(w.Body.Bytes(), &response)
+
+	ops := make(map[string]string)
+	for _, op := range response.Diff {
+		ops[op.Path] = op.Op
+	}
+
+	if ops["/tags/a~1b"] != "replace" {
+		t.Errorf("Expected /tags/a~1b to be replaced, got %v", ops)
+	}
+	if ops["/tags/c~0d"] != "replace" {
+		t.Errorf("Expected /tags/c~0d to be replaced, got %v", ops)
+	}
+}
+
+// TestTransformCapturesDebugTrace tests that values recorded via the
+// Debug helper injected into generated code are surfaced on the response,
+// separate from the transformed event itself.
+func TestTransformCapturesDebugTrace(t *testing.T) {
+	router := setupRouter()
+
+	event := map[string]interface{}{"event_id": "test123"}
+	beforeSendCode := `Debug("before", event["event_id"])
+	Debug("sampled", 0.25)
+	return event`
+
+	payload := map[string]interface{}{
+		"event":          event,
+		"beforeSendCode": beforeSendCode,
+	}
+	body, _ := json.Marshal(payload)
+	req, _ := http.NewRequest("POST", "/transform", bytes.NewBuffer(body))
+	req.Header.Set("Content-Type", "application/json")
+
+	w := httptest.NewRecorder()
+	router.ServeHTTP(w, req)
+
+	if w.Code != http.StatusOK {
+		t.Fatalf("Expected status 200, got %d: %s", w.Code, w.Body.String())
+	}
+
+	var response TransformResponse
+	json.Unmarshal(w.Body.Bytes(), &response)
+
+	if len(response.Trace) != 2 {
+		t.Fatalf("Expected 2 trace entries, got %d: %v", len(response.Trace), response.Trace)
+	}
+	if response.Trace[0].Key != "before" || response.Trace[0].Value != "test123" {
+		t.Errorf("Expected first trace entry {before, test123}, got %+v", response.Trace[0])
+	}
+	if response.Trace[1].Key != "sampled" || response.Trace[1].Value != 0.25 {
+		t.Errorf("Expected second trace entry {sampled, 0.25}, got %+v", response.Trace[1])
+	}
+}
+
+// TestPythonTransformReturnsNilDropsEvent tests that the Python executor
+// drops the event when before_send returns None, mirroring
+// TestTransformReturnsNilDropsEvent for the Go executor.
+func TestPythonTransformReturnsNilDropsEvent(t *testing.T) {
+	router := setupRouter()
+
+	payload := map[string]interface{}{
+		"event":          map[string]interface{}{"event_id": "test123"},
+		"beforeSendCode": "    return None",
+		"sdk":            "python",
+	}
+
+	body, _ := json.Marshal(payload)
+	req, _ := http.NewRequest("POST", "/transform", bytes.NewBuffer(body))
+	req.Header.Set("Content-Type", "application/json")
+
+	w := httptest.NewRecorder()
+	router.ServeHTTP(w, req)
+
+	if w.Code != http.StatusOK {
+		t.Fatalf("Expected status 200, got %d: %s", w.Code, w.Body.String())
+	}
+
+	var response map[string]interface{}
+	json.Unmarshal(w.Body.Bytes(), &response)
+
+	if response["success"] != true {
+		t.Errorf("Expected success=true, got %v", response["success"])
+	}
+	if response["transformedEvent"] != nil {
+		t.Errorf("Expected transformedEvent=nil, got %v", response["transformedEvent"])
+	}
+}
+
+// TestJavaScriptTransformReturnsNilDropsEvent tests that the JavaScript
+// executor drops the event when beforeSend returns null/undefined,
+// mirroring TestTransformReturnsNilDropsEvent for the Go executor.
+func TestJavaScriptTransformReturnsNilDropsEvent(t *testing.T) {
+	router := setupRouter()
+
+	payload := map[string]interface{}{
+		"event":          map[string]interface{}{"event_id": "test123"},
+		"beforeSendCode": "return null;",
+		"sdk":            "javascript",
+	}
+
+	body, _ := json.Marshal(payload)
+	req, _ := http.NewRequest("POST", "/transform", bytes.NewBuffer(body))
+	req.Header.Set("Content-Type", "application/json")
+
+	w := httptest.NewRecorder()
+	router.ServeHTTP(w, req)
+
+	if w.Code != http.StatusOK {
+		t.Fatalf("Expected status 200, got %d: %s", w.Code, w.Body.String())
+	}
+
+	var response map[string]interface{}
+	json.Unmarshal(w.Body.Bytes(), &response)
+
+	if response["success"] != true {
+		t.Errorf("Expected success=true, got %v", response["success"])
+	}
+	if response["transformedEvent"] != nil {
+		t.Errorf("Expected transformedEvent=nil, got %v", response["transformedEvent"])
+	}
+}
+
+// TestPythonTracesSamplerReturnsFloat tests that the Python executor can
+// drive tracesSampler and return a numeric sample rate, mirroring
+// TestTracesSamplerReturnsFloat for the Go executor.
+func TestPythonTracesSamplerReturnsFloat(t *testing.T) {
+	router := setupRouter()
+
+	payload := map[string]interface{}{
+		"event":          map[string]interface{}{"transactionContext": map[string]interface{}{"name": "GET /health"}},
+		"beforeSendCode": "    return 0.5",
+		"sdk":            "python",
+	}
+
+	body, _ := json.Marshal(payload)
+	req, _ := http.NewRequest("POST", "/transform", bytes.NewBuffer(body))
+	req.Header.Set("Content-Type", "application/json")
+
+	w := httptest.NewRecorder()
+	router.ServeHTTP(w, req)
+
+	if w.Code != http.StatusOK {
+		t.Fatalf("Expected status 200, got %d: %s", w.Code, w.Body.String())
+	}
+
+	var response map[string]interface{}
+	json.Unmarshal(w.Body.Bytes(), &response)
+
+	sampleRate, ok := response["transformedEvent"].(float64)
+	if !ok {
+		t.Fatalf("Expected transformedEvent to be a float64, got %T", response["transformedEvent"])
+	}
+	if sampleRate != 0.5 {
+		t.Errorf("Expected sample rate 0.5, got %v", sampleRate)
+	}
+}
+
+// TestJavaScriptTracesSamplerReturnsFloat tests that the JavaScript
+// executor can drive tracesSampler and return a numeric sample rate,
+// mirroring TestTracesSamplerReturnsFloat for the Go executor.
+func TestJavaScriptTracesSamplerReturnsFloat(t *testing.T) {
+	router := setupRouter()
+
+	payload := map[string]interface{}{
+		"event":          map[string]interface{}{"transactionContext": map[string]interface{}{"name": "GET /health"}},
+		"beforeSendCode": "return 0.5;",
+		"sdk":            "javascript",
+	}
+
+	body, _ := json.Marshal(payload)
+	req, _ := http.NewRequest("POST", "/transform", bytes.NewBuffer(body))
+	req.Header.Set("Content-Type", "application/json")
+
+	w := httptest.NewRecorder()
+	router.ServeHTTP(w, req)
+
+	if w.Code != http.StatusOK {
+		t.Fatalf("Expected status 200, got %d: %s", w.Code, w.Body.String())
+	}
+
+	var response map[string]interface{}
+	json.Unmarshal(w.Body.Bytes(), &response)
+
+	sampleRate, ok := response["transformedEvent"].(float64)
+	if !ok {
+		t.Fatalf("Expected transformedEvent to be a float64, got %T", response["transformedEvent"])
+	}
+	if sampleRate != 0.5 {
+		t.Errorf("Expected sample rate 0.5, got %v", sampleRate)
+	}
+}
+
+// TestTransformWithBodySDKFieldDispatchesToPython tests that the body's
+// "sdk" field alone (no "/:sdk/..." path prefix) is enough to route to a
+// non-Go executor.
+func TestTransformWithBodySDKFieldDispatchesToPython(t *testing.T) {
+	router := setupRouter()
+
+	payload := map[string]interface{}{
+		"event":          map[string]interface{}{"event_id": "test123"},
+		"beforeSendCode": "    event['event_id'] = 'modified'\n    return event",
+		"sdk":            "python",
+	}
+
+	body, _ := json.Marshal(payload)
+	req, _ := http.NewRequest("POST", "/transform", bytes.NewBuffer(body))
+	req.Header.Set("Content-Type", "application/json")
+
+	w := httptest.NewRecorder()
+	router.ServeHTTP(w, req)
+
+	if w.Code != http.StatusOK {
+		t.Fatalf("Expected status 200, got %d: %s", w.Code, w.Body.String())
+	}
+
+	var response map[string]interface{}
+	json.Unmarshal(w.Body.Bytes(), &response)
+
+	transformedEvent, ok := response["transformedEvent"].(map[string]interface{})
+	if !ok {
+		t.Fatalf("Expected transformedEvent to be a map, got %v", response["transformedEvent"])
+	}
+	if transformedEvent["event_id"] != "modified" {
+		t.Errorf("Expected event_id=modified, got %v", transformedEvent["event_id"])
+	}
+}
+
+// TestTransformInfiniteLoopTimesOut tests that code that never returns is
+// killed by the sandbox's wall-clock timeout and reported as a 408, rather
+// than hanging the request indefinitely.
+func TestTransformInfiniteLoopTimesOut(t *testing.T) {
+	router := setupRouter()
+
+	payload := map[string]interface{}{
+		"event":          map[string]interface{}{"event_id": "test123"},
+		"beforeSendCode": "for {}",
+	}
+
+	body, _ := json.Marshal(payload)
+	req, _ := http.NewRequest("POST", "/transform", bytes.NewBuffer(body))
+	req.Header.Set("Content-Type", "application/json")
+
+	w := httptest.NewRecorder()
+	router.ServeHTTP(w, req)
+
+	if w.Code != http.StatusRequestTimeout {
+		t.Fatalf("Expected status 408, got %d: %s", w.Code, w.Body.String())
+	}
+
+	var response map[string]interface{}
+	json.Unmarshal(w.Body.Bytes(), &response)
+
+	if response["success"] != false {
+		t.Errorf("Expected success=false, got %v", response["success"])
+	}
+	if !strings.Contains(response["error"].(string), "timed out") {
+		t.Errorf("Expected a timeout error, got %v", response["error"])
+	}
+}
+
+// TestTransformOversizeResultReturns413 tests that a result too large to
+// reasonably return to a client is rejected with a 413, rather than
+// flooding the response body.
+func TestTransformOversizeResultReturns413(t *testing.T) {
+	router := setupRouter()
+
+	payload := map[string]interface{}{
+		"event":          map[string]interface{}{"event_id": "test123"},
+		"beforeSendCode": "return make([]byte, 10<<20)",
+	}
+
+	body, _ := json.Marshal(payload)
+	req, _ := http.NewRequest("POST", "/transform", bytes.NewBuffer(body))
+	req.Header.Set("Content-Type", "application/json")
+
+	w := httptest.NewRecorder()
+	router.ServeHTTP(w, req)
+
+	if w.Code != http.StatusRequestEntityTooLarge {
+		t.Fatalf("Expected status 413, got %d: %s", w.Code, w.Body.String())
+	}
+
+	var response map[string]interface{}
+	json.Unmarshal(w.Body.Bytes(), &response)
+
+	if response["success"] != false {
+		t.Errorf("Expected success=false, got %v", response["success"])
+	}
+}
+
+// TestTransformDeeplyNestedResultReturns400 tests that a returned structure
+// nested deeper than the sandbox's limit is rejected before it's ever
+// marshaled back to the client.
+func TestTransformDeeplyNestedResultReturns400(t *testing.T) {
+	router := setupRouter()
+
+	payload := map[string]interface{}{
+		"event": map[string]interface{}{"event_id": "test123"},
+		"beforeSendCode": `root := map[string]interface{}{}
+	cur := root
+	for i := 0; i < 40; i++ {
+		next := map[string]interface{}{}
+		cur["nested"] = next
+		cur = next
+	}
+	return root`,
+	}
+
+	body, _ := json.Marshal(payload)
+	req, _ := http.NewRequest("POST", "/transform", bytes.NewBuffer(body))
+	req.Header.Set("Content-Type", "application/json")
+
+	w := httptest.NewRecorder()
+	router.ServeHTTP(w, req)
+
+	if w.Code != http.StatusBadRequest {
+		t.Fatalf("Expected status 400, got %d: %s", w.Code, w.Body.String())
+	}
+
+	var response map[string]interface{}
+	json.Unmarshal(w.Body.Bytes(), &response)
+
+	if response["success"] != false {
+		t.Errorf("Expected success=false, got %v", response["success"])
+	}
+	if !strings.Contains(response["error"].(string), "nested") {
+		t.Errorf("Expected a nesting-depth error, got %v", response["error"])
+	}
+}
+
+// TestMetricsEndpointExposesCounters tests that /metrics exposes Prometheus
+// counters labeled by sdk and outcome after a mix of transform scenarios:
+// a successful transform, a dropped event, invalid syntax, and a panic.
+func TestMetricsEndpointExposesCounters(t *testing.T) {
+	router := setupRouter()
+
+	scenarios := []map[string]interface{}{
+		{
+			"event":          map[string]interface{}{"event_id": "test123"},
+			"beforeSendCode": "event[\"modified\"] = true\nreturn event",
+		},
+		{
+			"event":          map[string]interface{}{"event_id": "test123"},
+			"beforeSendCode": "return nil",
+		},
+		{
+			"event":          map[string]interface{}{"event_id": "test123"},
+			"beforeSendCode": "this is not valid go",
+		},
+		{
+			"event":          map[string]interface{}{"event_id": "test123"},
+			"beforeSendCode": "panic(\"boom\")",
+		},
+	}
+
+	for _, payload := range scenarios {
+		body, _ := json.Marshal(payload)
+		req, _ := http.NewRequest("POST", "/transform", bytes.NewBuffer(body))
+		req.Header.Set("Content-Type", "application/json")
+		w := httptest.NewRecorder()
+		router.ServeHTTP(w, req)
+	}
+
+	req, _ := http.NewRequest("GET", "/metrics", nil)
+	w := httptest.NewRecorder()
+	router.ServeHTTP(w, req)
+
+	if w.Code != http.StatusOK {
+		t.Fatalf("Expected status 200, got %d", w.Code)
+	}
+
+	metrics := w.Body.String()
+	for _, want := range []string{
+		`playground_transform_requests_total{outcome="success",sdk="go"}`,
+		`playground_transform_requests_total{outcome="dropped",sdk="go"}`,
+		`playground_transform_requests_total{outcome="compile_error",sdk="go"}`,
+		`playground_transform_requests_total{outcome="runtime_error",sdk="go"}`,
+	} {
+		if !strings.Contains(metrics, want) {
+			t.Errorf("Expected /metrics to contain %q, got:\n%s", want, metrics)
+		}
+	}
+}
+
+// TestBatchTransformDropsHalfTheEvents tests /transform/batch against 100
+// events where even-indexed ones are dropped (beforeSend returns nil) and
+// odd-indexed ones pass through modified.
+func TestBatchTransformDropsHalfTheEvents(t *testing.T) {
+	router := setupRouter()
+
+	events := make([]map[string]interface{}, 100)
+	for i := range events {
+		events[i] = map[string]interface{}{"event_id": fmt.Sprintf("event-%d", i)}
+	}
+
+	payload := map[string]interface{}{
+		"beforeSendCode": `if id, _ := event["event_id"].(string); strings.HasSuffix(id, "0") || strings.HasSuffix(id, "2") || strings.HasSuffix(id, "4") || strings.HasSuffix(id, "6") || strings.HasSuffix(id, "8") {
+		return nil
+	}
+	event["seen"] = true
+	return event`,
+		"events": events,
+	}
+
+	body, _ := json.Marshal(payload)
+	req, _ := http.NewRequest("POST", "/transform/batch", bytes.NewBuffer(body))
+	req.Header.Set("Content-Type", "application/json")
+
+	w := httptest.NewRecorder()
+	router.ServeHTTP(w, req)
+
+	if w.Code != http.StatusOK {
+		t.Fatalf("Expected status 200, got %d: %s", w.Code, w.Body.String())
+	}
+
+	var response struct {
+		Results []map[string]interface{} `json:"results"`
+	}
+	json.Unmarshal(w.Body.Bytes(), &response)
+
+	if len(response.Results) != 100 {
+		t.Fatalf("Expected 100 results, got %d", len(response.Results))
+	}
+
+	dropped, kept := 0, 0
+	for i, result := range response.Results {
+		if result["index"].(float64) != float64(i) {
+			t.Errorf("Expected result %d to have index %d, got %v", i, i, result["index"])
+		}
+		if result["success"] != true {
+			t.Errorf("Expected result %d to succeed, got %v", i, result)
+			continue
+		}
+		if result["transformedEvent"] == nil {
+			dropped++
+		} else {
+			kept++
+		}
+	}
+	if dropped != 50 || kept != 50 {
+		t.Errorf("Expected 50 dropped and 50 kept, got %d dropped, %d kept", dropped, kept)
+	}
+}
+
+// TestBatchTransformPartialFailure tests that a panic on one event in a
+// batch doesn't fail the whole request - the batch still responds 200,
+// with the failing item's own result marked unsuccessful.
+func TestBatchTransformPartialFailure(t *testing.T) {
+	router := setupRouter()
+
+	events := []map[string]interface{}{
+		{"event_id": "ok-1"},
+		{"event_id": "boom"},
+		{"event_id": "ok-2"},
+	}
+
+	payload := map[string]interface{}{
+		"beforeSendCode": `if id, _ := event["event_id"].(string); id == "boom" {
+		panic("simulated failure")
+	}
+	return event`,
+		"events": events,
+	}
+
+	body, _ := json.Marshal(payload)
+	req, _ := http.NewRequest("POST", "/transform/batch", bytes.NewBuffer(body))
+	req.Header.Set("Content-Type", "application/json")
+
+	w := httptest.NewRecorder()
+	router.ServeHTTP(w, req)
+
+	if w.Code != http.StatusOK {
+		t.Fatalf("Expected status 200, got %d: %s", w.Code, w.Body.String())
+	}
+
+	var response struct {
+		Results []map[string]interface{} `json:"results"`
+	}
+	json.Unmarshal(w.Body.Bytes(), &response)
+
+	if len(response.Results) != 3 {
+		t.Fatalf("Expected 3 results, got %d", len(response.Results))
+	}
+	if response.Results[0]["success"] != true {
+		t.Errorf("Expected event 0 to succeed, got %v", response.Results[0])
+	}
+	if response.Results[1]["success"] != false {
+		t.Errorf("Expected event 1 to fail, got %v", response.Results[1])
+	}
+	if response.Results[1]["error"] == nil || response.Results[1]["error"] == "" {
+		t.Errorf("Expected event 1 to carry an error message, got %v", response.Results[1])
+	}
+	if response.Results[2]["success"] != true {
+		t.Errorf("Expected event 2 to succeed, got %v", response.Results[2])
+	}
+}
+
+// TestBatchTransformRecordsMetrics tests that /transform/batch reports each
+// item's outcome to the same playground_transform_requests_total counters
+// /transform does, not just the single-event path.
+func TestBatchTransformRecordsMetrics(t *testing.T) {
+	router := setupRouter()
+
+	events := []map[string]interface{}{
+		{"event_id": "ok"},
+		{"event_id": "dropped"},
+		{"event_id": "boom"},
+	}
+
+	payload := map[string]interface{}{
+		"beforeSendCode": `if id, _ := event["event_id"].(string); id == "boom" {
+		panic("simulated failure")
+	}
+	if id, _ := event["event_id"].(string); id == "dropped" {
+		return nil
+	}
+	return event`,
+		"events": events,
+	}
+
+	body, _ := json.Marshal(payload)
+	req, _ := http.NewRequest("POST", "/transform/batch", bytes.NewBuffer(body))
+	req.Header.Set("Content-Type", "application/json")
+
+	w := httptest.NewRecorder()
+	router.ServeHTTP(w, req)
+
+	if w.Code != http.StatusOK {
+		t.Fatalf("Expected status 200, got %d: %s", w.Code, w.Body.String())
+	}
+
+	metricsReq, _ := http.NewRequest("GET", "/metrics", nil)
+	metricsW := httptest.NewRecorder()
+	router.ServeHTTP(metricsW, metricsReq)
+
+	metrics := metricsW.Body.String()
+	for _, want := range []string{
+		`playground_transform_requests_total{outcome="success",sdk="go"}`,
+		`playground_transform_requests_total{outcome="dropped",sdk="go"}`,
+		`playground_transform_requests_total{outcome="runtime_error",sdk="go"}`,
+	} {
+		if !strings.Contains(metrics, want) {
+			t.Errorf("Expected /metrics to contain %q after a batch transform, got:\n%s", want, metrics)
+		}
+	}
+}
+
+// TestBatchTransformDiffForUnityCleanup mirrors TestComplexUnityMetadataCleanup,
+// asserting the batch endpoint's diff correctly records the removed key.
+func TestBatchTransformDiffForUnityCleanup(t *testing.T) {
+	router := setupRouter()
+
+	event := map[string]interface{}{
+		"event_id": "test123",
+		"contexts": map[string]interface{}{
+			"unity": map[string]interface{}{
+				"debug_info":      "sensitive data",
+				"internal_state": "internal",
+			},
+		},
+	}
+
+	payload := map[string]interface{}{
+		"beforeSendCode": `if contexts, ok := event["contexts"].(map[string]interface{}); ok {
+		if unity, ok := contexts["unity"].(map[string]interface{}); ok {
+			delete(unity, "debug_info")
+		}
+	}
+	return event`,
+		"events": []map[string]interface{}{event},
+	}
+
+	body, _ := json.Marshal(payload)
+	req, _ := http.NewRequest("POST", "/transform/batch", bytes.NewBuffer(body))
+	req.Header.Set("Content-Type", "application/json")
+
+	w := httptest.NewRecorder()
+	router.ServeHTTP(w, req)
+
+	if w.Code != http.StatusOK {
+		t.Fatalf("Expected status 200, got %d: %s", w.Code, w.Body.String())
+	}
+
+	var response struct {
+		Results []struct {
+			Diff []map[string]interface{} `json:"diff"`
+		} `json:"results"`
+	}
+	json.Unmarshal(w.Body.Bytes(), &response)
+
+	if len(response.Results) != 1 {
+		t.Fatalf("Expected 1 result, got %d", len(response.Results))
+	}
+
+	found := false
+	for _, op := range response.Results[0].Diff {
+		if op["op"] == "remove" && op["path"] == "/contexts/unity/debug_info" {
+			found = true
+		}
+	}
+	if !found {
+		t.Errorf("Expected diff to contain {op:remove, path:/contexts/unity/debug_info}, got %v", response.Results[0].Diff)
+	}
+}
+
+// TestSnippetCreateFetchRunRoundTrip tests the full lifecycle: create a
+// snippet via POST /snippets, fetch it back via GET /snippets/:id, then
+// run it via POST /snippets/:id/run against its own sampleEvent.
+func TestSnippetCreateFetchRunRoundTrip(t *testing.T) {
+	snippetStore = newInMemorySnippetStore()
+	router := setupRouter()
+
+	payload := map[string]interface{}{
+		"name": "Tag Environment",
+		"code": `event["tagged"] = true
+	return event`,
+		"sampleEvent": map[string]interface{}{"event_id": "test123"},
+	}
+	body, _ := json.Marshal(payload)
+	req, _ := http.NewRequest("POST", "/snippets", bytes.NewBuffer(body))
+	req.Header.Set("Content-Type", "application/json")
+
+	w := httptest.NewRecorder()
+	router.ServeHTTP(w, req)
+
+	if w.Code != http.StatusCreated {
+		t.Fatalf("Expected status 201, got %d: %s", w.Code, w.Body.String())
+	}
+
+	var created struct {
+		ID string `json:"id"`
+	}
+	json.Unmarshal(w.Body.Bytes(), &created)
+
+	getReq, _ := http.NewRequest("GET", "/snippets/"+created.ID, nil)
+	getW := httptest.NewRecorder()
+	router.ServeHTTP(getW, getReq)
+	if getW.Code != http.StatusOK {
+		t.Fatalf("Expected status 200, got %d", getW.Code)
+	}
+
+	runReq, _ := http.NewRequest("POST", "/snippets/"+created.ID+"/run", nil)
+	runW := httptest.NewRecorder()
+	router.ServeHTTP(runW, runReq)
+
+	if runW.Code != http.StatusOK {
+		t.Fatalf("Expected status 200, got %d: %s", runW.Code, runW.Body.String())
+	}
+
+	var response TransformResponse
+	json.Unmarshal(runW.Body.Bytes(), &response)
+
+	transformedEvent, ok := response.TransformedEvent.(map[string]interface{})
+	if !ok {
+		t.Fatalf("Expected transformedEvent to be a map, got %v", response.TransformedEvent)
+	}
+	if transformedEvent["tagged"] != true {
+		t.Errorf("Expected tagged=true, got %v", transformedEvent["tagged"])
+	}
+	if response.AppliedSnippet == nil || response.AppliedSnippet.ID != created.ID {
+		t.Errorf("Expected appliedSnippet.id=%s, got %v", created.ID, response.AppliedSnippet)
+	}
+}
+
+// TestSnippetRunReturnsNilDropsEvent mirrors TestTransformReturnsNilDropsEvent,
+// confirming a snippet that returns nil drops the event the same way when
+// run via /snippets/:id/run as it does inline via /transform.
+func TestSnippetRunReturnsNilDropsEvent(t *testing.T) {
+	snippetStore = newInMemorySnippetStore()
+	if err := snippetStore.Save(Snippet{
+		ID:          "drop-everything",
+		Name:        "Drop Everything",
+		SDK:         "go",
+		Code:        `return nil`,
+		SampleEvent: map[string]interface{}{"event_id": "test123"},
+	}); err != nil {
+		t.Fatalf("failed to seed snippet: %v", err)
+	}
+	router := setupRouter()
+
+	req, _ := http.NewRequest("POST", "/snippets/drop-everything/run", nil)
+	w := httptest.NewRecorder()
+	router.ServeHTTP(w, req)
+
+	if w.Code != http.StatusOK {
+		t.Fatalf("Expected status 200, got %d: %s", w.Code, w.Body.String())
+	}
+
+	var response TransformResponse
+	json.Unmarshal(w.Body.Bytes(), &response)
+
+	if response.TransformedEvent != nil {
+		t.Errorf("Expected transformedEvent=nil, got %v", response.TransformedEvent)
+	}
+}
+
+// TestSnippetListPagination tests that GET /snippets honors limit/offset
+// query params rather than always returning the full library.
+func TestSnippetListPagination(t *testing.T) {
+	snippetStore = newInMemorySnippetStore()
+	for i := 0; i < 5; i++ {
+		if err := snippetStore.Save(Snippet{
+			ID:   fmt.Sprintf("snippet-%d", i),
+			Name: fmt.Sprintf("Snippet %d", i),
+			SDK:  "go",
+			Code: "return event",
+		}); err != nil {
+			t.Fatalf("failed to seed snippet %d: %v", i, err)
+		}
+	}
+	router := setupRouter()
+
+	req, _ := http.NewRequest("GET", "/snippets?limit=2&offset=1", nil)
+	w := httptest.NewRecorder()
+	router.ServeHTTP(w, req)
+
+	if w.Code != http.StatusOK {
+		t.Fatalf("Expected status 200, got %d", w.Code)
+	}
+
+	var response struct {
+		Snippets []Snippet `json:"snippets"`
+		Total    int       `json:"total"`
+		Limit    int       `json:"limit"`
+		Offset   int       `json:"offset"`
+	}
+	json.Unmarshal(w.Body.Bytes(), &response)
+
+	if response.Total != 5 {
+		t.Errorf("Expected total=5, got %d", response.Total)
+	}
+	if len(response.Snippets) != 2 {
+		t.Errorf("Expected a page of 2 snippets, got %d", len(response.Snippets))
+	}
+	if response.Snippets[0].ID != "snippet-1" {
+		t.Errorf("Expected the page to start at snippet-1, got %s", response.Snippets[0].ID)
+	}
+}
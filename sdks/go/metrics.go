@@ -0,0 +1,56 @@
+package main
+
+import (
+	"encoding/json"
+	"time"
+
+	"github.com/prometheus/client_golang/prometheus"
+	"github.com/prometheus/client_golang/prometheus/promauto"
+)
+
+// transformRequestsTotal counts every transform by the sdk it ran against
+// and how it ended up: "success", "compile_error", "runtime_error",
+// "dropped" (beforeSend returned nil), or "timeout".
+var transformRequestsTotal = promauto.NewCounterVec(prometheus.CounterOpts{
+	Name: "playground_transform_requests_total",
+	Help: "Total number of /transform requests, labeled by sdk and outcome.",
+}, []string{"sdk", "outcome"})
+
+var transformDuration = promauto.NewHistogram(prometheus.HistogramOpts{
+	Name:    "playground_transform_duration_seconds",
+	Help:    "Wall-clock time spent executing a transform, across all sdks and outcomes.",
+	Buckets: prometheus.DefBuckets,
+})
+
+var transformResultBytes = promauto.NewHistogram(prometheus.HistogramOpts{
+	Name:    "playground_transform_result_bytes",
+	Help:    "Size, in bytes, of a transform's marshaled transformedEvent.",
+	Buckets: prometheus.ExponentialBuckets(64, 4, 8),
+})
+
+var tracesSamplerRate = promauto.NewHistogram(prometheus.HistogramOpts{
+	Name:    "playground_traces_sampler_rate",
+	Help:    "Sample rate returned by tracesSampler code, bucketed across its 0..1 range.",
+	Buckets: prometheus.LinearBuckets(0, 0.1, 11),
+})
+
+// observeTransform records a completed transform's outcome, duration, and -
+// when it produced a value - its marshaled size and (for tracesSampler)
+// sample rate. Called once per transform, regardless of how it ended.
+func observeTransform(sdk, outcome string, start time.Time, value interface{}) {
+	transformRequestsTotal.WithLabelValues(sdk, outcome).Inc()
+	transformDuration.Observe(time.Since(start).Seconds())
+
+	if value == nil || outcome == "compile_error" || outcome == "timeout" {
+		return
+	}
+
+	if rate, ok := value.(float64); ok {
+		tracesSamplerRate.Observe(rate)
+		return
+	}
+
+	if data, err := json.Marshal(value); err == nil {
+		transformResultBytes.Observe(float64(len(data)))
+	}
+}
@@ -0,0 +1,127 @@
+package main
+
+import (
+	"bytes"
+	"context"
+	"encoding/json"
+	"fmt"
+	"strings"
+)
+
+// pythonExecutor runs beforeSend/tracesSampler code written in Python
+// against an installed python3 interpreter. The event is passed via argv
+// (not embedded in the script source) so we never have to worry about the
+// user's code or the event JSON colliding with Go's string-quoting rules.
+type pythonExecutor struct{}
+
+// pythonHarness wraps the user's code as the body of a function named
+// before_send, then calls it with the event parsed from argv and prints
+// the result as JSON (or "null") on stdout, followed by traceSentinel and
+// the JSON array of {key, value} entries recorded via debug().
+const pythonHarness = `
+import json
+import sys
+
+_trace = []
+def debug(key, value):
+    _trace.append({"key": key, "value": value})
+
+def before_send(event, hint):
+%s
+
+event = json.loads(sys.argv[1])
+result = before_send(event, {})
+if result is None:
+    print("null")
+elif isinstance(result, (int, float)) and not isinstance(result, bool):
+    print(float(result))
+else:
+    print(json.dumps(result))
+print("` + traceSentinel + `")
+print(json.dumps(_trace))
+`
+
+func indentPythonBody(code string) string {
+	lines := strings.Split(code, "\n")
+	for i, line := range lines {
+		lines[i] = "    " + line
+	}
+	return strings.Join(lines, "\n")
+}
+
+func (pythonExecutor) Validate(code string) ValidationResponse {
+	script := fmt.Sprintf(pythonHarness, indentPythonBody(code))
+
+	ctx, cancel := context.WithTimeout(context.Background(), sandboxTimeout)
+	defer cancel()
+
+	// compile() parses and compiles the harnessed script without running
+	// it, so before_send is never called during validation.
+	cmd := sandboxedInterpreterCommand(ctx, "python3", "-c", "import sys; compile(sys.argv[1], 'before_send.py', 'exec')", script)
+	var stderr bytes.Buffer
+	cmd.Stderr = &stderr
+
+	if err := cmd.Run(); err != nil {
+		return ValidationResponse{
+			Valid: false,
+			Errors: []ValidationError{
+				{Message: strings.TrimSpace(stderr.String())},
+			},
+		}
+	}
+
+	return ValidationResponse{Valid: true, Errors: []ValidationError{}}
+}
+
+func (pythonExecutor) Transform(event map[string]interface{}, code string) (*ExecutionResult, error) {
+	eventJSON, err := json.Marshal(event)
+	if err != nil {
+		return nil, fmt.Errorf("failed to marshal event: %w", err)
+	}
+
+	script := fmt.Sprintf(pythonHarness, indentPythonBody(code))
+
+	ctx, cancel := context.WithTimeout(context.Background(), sandboxTimeout)
+	defer cancel()
+
+	cmd := sandboxedInterpreterCommand(ctx, "python3", "-c", script, string(eventJSON))
+	var stdout, stderr bytes.Buffer
+	cmd.Stdout = &stdout
+	cmd.Stderr = &stderr
+
+	if err := cmd.Run(); err != nil {
+		errorMsg := strings.TrimSpace(stderr.String())
+		if errorMsg == "" {
+			errorMsg = err.Error()
+		}
+		if ctx.Err() == context.DeadlineExceeded {
+			return nil, &TimeoutError{Message: fmt.Sprintf("execution timed out after %s", sandboxTimeout)}
+		}
+		if strings.Contains(errorMsg, "SyntaxError") || strings.Contains(errorMsg, "IndentationError") {
+			return nil, &CompileError{Message: errorMsg}
+		}
+		return nil, &RuntimeError{Message: errorMsg, Traceback: errorMsg}
+	}
+
+	resultPart, tracePart := splitTraceOutput(stdout.String())
+
+	trace, err := parseTrace(tracePart)
+	if err != nil {
+		return nil, &RuntimeError{Message: err.Error()}
+	}
+
+	if resultPart == "null" {
+		return &ExecutionResult{Value: nil, Trace: trace}, nil
+	}
+
+	var result interface{}
+	if err := json.Unmarshal([]byte(resultPart), &result); err != nil {
+		return nil, &RuntimeError{Message: fmt.Sprintf("failed to parse result: %v", err)}
+	}
+
+	if err := enforceResultLimits(result); err != nil {
+		return nil, err
+	}
+
+	return &ExecutionResult{Value: result, Trace: trace}, nil
+}
@@ -0,0 +1,230 @@
+package main
+
+import (
+	"context"
+	"encoding/json"
+	"fmt"
+	"go/ast"
+	"go/parser"
+	"go/token"
+	"math"
+	"os"
+	"os/exec"
+	"strconv"
+	"time"
+)
+
+// sandboxTimeout bounds how long a single transform's subprocess may run
+// before it is killed. Overridable via PLAYGROUND_EXEC_TIMEOUT_MS.
+var sandboxTimeout = 2 * time.Second
+
+// buildTimeout bounds `go mod tidy`/`go build`, separately from
+// sandboxTimeout: those run the go toolchain, not user code, and a cold
+// module/build cache can legitimately take longer to resolve or compile
+// than it's reasonable to let a single transform's execution run for.
+// Sharing sandboxTimeout between them meant ordinary build latency could
+// surface as the same "execution timed out" a runaway user snippet would.
+// Overridable via PLAYGROUND_BUILD_TIMEOUT_MS.
+var buildTimeout = 15 * time.Second
+
+// maxResultDepth bounds how deeply nested a transformedEvent may be;
+// returning something deeper trips the sandbox before it's ever marshaled
+// back to the client.
+const maxResultDepth = 32
+
+// SandboxLimits describes the resource limits the sandbox enforces, echoed
+// back to clients so they know what killed (or could kill) a transform.
+type SandboxLimits struct {
+	TimeoutSeconds   float64  `json:"timeoutSeconds"`
+	MaxMemoryBytes   int64    `json:"maxMemoryBytes"`
+	MaxCPUSeconds    int64    `json:"maxCpuSeconds"`
+	MaxOpenFiles     int64    `json:"maxOpenFiles"`
+	MaxFileSizeBytes int64    `json:"maxFileSizeBytes"`
+	MaxResultBytes   int64    `json:"maxResultBytes"`
+	MaxResultDepth   int      `json:"maxResultDepth"`
+	AllowedImports   []string `json:"allowedImports"`
+}
+
+var effectiveLimits = SandboxLimits{
+	TimeoutSeconds:   sandboxTimeout.Seconds(),
+	MaxMemoryBytes:   2 << 30, // the go toolchain itself needs headroom, not just the user snippet
+	MaxCPUSeconds:    cpuSecondsFor(sandboxTimeout),
+	MaxOpenFiles:     256,
+	MaxFileSizeBytes: 10 << 20,
+	MaxResultBytes:   512 << 10, // overridable via PLAYGROUND_MAX_RESULT_BYTES
+	MaxResultDepth:   maxResultDepth,
+	AllowedImports:   []string{"fmt", "strings", "regexp", "encoding/json", "time", "strconv", "math", "sort"},
+}
+
+// buildCPUSeconds is buildTimeout's CPU-time rlimit analog to
+// effectiveLimits.MaxCPUSeconds, applied to `go mod tidy`/`go build` instead
+// of user-code execution. It isn't part of SandboxLimits/effectiveLimits
+// since it isn't a limit on the user's code - it's not echoed to clients.
+var buildCPUSeconds = cpuSecondsFor(buildTimeout)
+
+// cpuSecondsFor derives a CPU-time rlimit from a wall-clock timeout.
+// `go run` doesn't exec into the binary it builds, so killing the sh
+// process ctx's deadline signals doesn't reach an already-forked child;
+// the CPU ulimit is the backstop that actually reaps a runaway child, so
+// it needs to track its wall-clock counterpart rather than sit at a fixed,
+// much larger value.
+func cpuSecondsFor(d time.Duration) int64 {
+	return int64(math.Ceil(d.Seconds()))
+}
+
+func init() {
+	if v := os.Getenv("PLAYGROUND_EXEC_TIMEOUT_MS"); v != "" {
+		if ms, err := strconv.Atoi(v); err == nil && ms > 0 {
+			sandboxTimeout = time.Duration(ms) * time.Millisecond
+		}
+	}
+	if v := os.Getenv("PLAYGROUND_BUILD_TIMEOUT_MS"); v != "" {
+		if ms, err := strconv.Atoi(v); err == nil && ms > 0 {
+			buildTimeout = time.Duration(ms) * time.Millisecond
+		}
+	}
+	if v := os.Getenv("PLAYGROUND_MAX_RESULT_BYTES"); v != "" {
+		if n, err := strconv.ParseInt(v, 10, 64); err == nil && n > 0 {
+			effectiveLimits.MaxResultBytes = n
+		}
+	}
+	effectiveLimits.TimeoutSeconds = sandboxTimeout.Seconds()
+	effectiveLimits.MaxCPUSeconds = cpuSecondsFor(sandboxTimeout)
+	buildCPUSeconds = cpuSecondsFor(buildTimeout)
+}
+
+// enforceResultLimits checks a transform's result against the sandbox's
+// depth and size limits before it's ever handed back to a client. Depth is
+// checked first since a deeply nested structure is a more specific failure
+// than "too big" even when it's also that.
+func enforceResultLimits(value interface{}) error {
+	if depth := resultDepth(value); depth > effectiveLimits.MaxResultDepth {
+		return &CompileError{Message: fmt.Sprintf("transformedEvent is nested %d levels deep, exceeding the sandbox's limit of %d", depth, effectiveLimits.MaxResultDepth)}
+	}
+
+	data, err := json.Marshal(value)
+	if err != nil {
+		return nil // the caller's own marshaling will surface this error
+	}
+	if int64(len(data)) > effectiveLimits.MaxResultBytes {
+		return &ResultTooLargeError{Message: fmt.Sprintf("transformedEvent is %d bytes, exceeding the sandbox's limit of %d", len(data), effectiveLimits.MaxResultBytes)}
+	}
+	return nil
+}
+
+// resultDepth measures how deeply nested v's maps and slices go. Scalars
+// (including strings, which may themselves encode large blobs as base64)
+// are depth 1.
+func resultDepth(v interface{}) int {
+	switch val := v.(type) {
+	case map[string]interface{}:
+		max := 0
+		for _, child := range val {
+			if d := resultDepth(child); d > max {
+				max = d
+			}
+		}
+		return max + 1
+	case []interface{}:
+		max := 0
+		for _, child := range val {
+			if d := resultDepth(child); d > max {
+				max = d
+			}
+		}
+		return max + 1
+	default:
+		return 1
+	}
+}
+
+// disallowedPackageIdents lists package identifiers worth gating at all -
+// anything not in this set is assumed to be a user variable or type, not a
+// package reference, and is left alone.
+var disallowedPackageIdents = map[string]bool{
+	"fmt": true, "strings": true, "regexp": true, "json": true, "time": true,
+	"strconv": true, "math": true, "sort": true,
+	"os": true, "exec": true, "net": true, "syscall": true, "unsafe": true,
+	"plugin": true, "runtime": true, "io": true, "ioutil": true, "http": true,
+}
+
+// sandboxedCommand builds an exec.Cmd for running `go <goArgs...>` - the
+// compiled transform binary's actual execution (`go run`) - against dir
+// under the sandbox: a wall-clock timeout carried by ctx, GOMAXPROCS and
+// GOMEMLIMIT caps, and (on Linux) the rlimits in effectiveLimits, budgeted
+// against the user-code execution limit (sandboxTimeout/MaxCPUSeconds).
+func sandboxedCommand(ctx context.Context, dir string, goArgs ...string) *exec.Cmd {
+	cmd := rlimitedGoCommand(ctx, effectiveLimits.MaxCPUSeconds, goArgs...)
+	cmd.Dir = dir
+	cmd.Env = append(cmd.Environ(), "GOMAXPROCS=1", "GOMEMLIMIT=512MiB")
+	return cmd
+}
+
+// sandboxedBuildCommand is like sandboxedCommand, but for `go mod
+// tidy`/`go build` - the toolchain steps that prepare a transform for
+// execution rather than run it - so it's budgeted against buildTimeout/
+// buildCPUSeconds instead of the user-code execution limit.
+func sandboxedBuildCommand(ctx context.Context, dir string, goArgs ...string) *exec.Cmd {
+	cmd := rlimitedGoCommand(ctx, buildCPUSeconds, goArgs...)
+	cmd.Dir = dir
+	cmd.Env = append(cmd.Environ(), "GOMAXPROCS=1", "GOMEMLIMIT=512MiB")
+	return cmd
+}
+
+// sandboxedInterpreterCommand builds an exec.Cmd for running `<bin>
+// <args...>` (e.g. python3, node, or a compiled transform binary) under the
+// same wall-clock timeout and (on Linux) rlimits as sandboxedCommand, for
+// SDKs that execute via an interpreter rather than the Go toolchain.
+func sandboxedInterpreterCommand(ctx context.Context, bin string, args ...string) *exec.Cmd {
+	return rlimitedCommand(ctx, effectiveLimits.MaxCPUSeconds, bin, args...)
+}
+
+// checkImportAllowlist parses beforeSendCode as a function body and
+// rejects any reference to a package outside the sandbox's allowlist,
+// before a program is ever assembled or written to disk.
+func checkImportAllowlist(code string) error {
+	wrapped := "package main\nfunc _() {\n" + code + "\n}"
+	fset := token.NewFileSet()
+	file, err := parser.ParseFile(fset, "beforeSend.go", wrapped, 0)
+	if err != nil {
+		// Invalid syntax is reported with a better, line-accurate message
+		// by the real compile step; don't duplicate it here.
+		return nil
+	}
+
+	allowed := map[string]bool{}
+	for _, path := range effectiveLimits.AllowedImports {
+		allowed[importIdent(path)] = true
+	}
+
+	var rejected error
+	ast.Inspect(file, func(n ast.Node) bool {
+		if rejected != nil {
+			return false
+		}
+		sel, ok := n.(*ast.SelectorExpr)
+		if !ok {
+			return true
+		}
+		ident, ok := sel.X.(*ast.Ident)
+		if !ok || !disallowedPackageIdents[ident.Name] {
+			return true
+		}
+		if !allowed[ident.Name] {
+			rejected = fmt.Errorf("import %q is not permitted in sandboxed beforeSend code", ident.Name)
+		}
+		return true
+	})
+	return rejected
+}
+
+// importIdent returns the identifier an import path is referenced by in
+// code, e.g. "encoding/json" -> "json".
+func importIdent(path string) string {
+	for i := len(path) - 1; i >= 0; i-- {
+		if path[i] == '/' {
+			return path[i+1:]
+		}
+	}
+	return path
+}
@@ -0,0 +1,68 @@
+//go:build linux
+
+package main
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"os/exec"
+	"strings"
+	"syscall"
+)
+
+// rlimitedGoCommand builds a command that runs `go <goArgs...>` under the
+// sandbox's rlimits; see rlimitedCommand.
+func rlimitedGoCommand(ctx context.Context, cpuSeconds int64, goArgs ...string) *exec.Cmd {
+	return rlimitedCommand(ctx, cpuSeconds, "go", goArgs...)
+}
+
+// rlimitedCommand builds a command that runs `<bin> <args...>` under
+// POSIX rlimits (address space, CPU time, open files, written file size)
+// in addition to the wall-clock timeout carried by ctx. cpuSeconds is the
+// CPU-time rlimit (`ulimit -t`); callers pass a different budget for build
+// steps than for running user code, see sandboxedCommand/
+// sandboxedBuildCommand. The limits are applied via the shell's `ulimit`
+// builtin rather than syscall.Setrlimit in this process, since os/exec has
+// no way to apply rlimits to a child before it execs into the target
+// binary.
+func rlimitedCommand(ctx context.Context, cpuSeconds int64, bin string, args ...string) *exec.Cmd {
+	script := fmt.Sprintf(
+		"ulimit -v %d && ulimit -t %d && ulimit -n %d && ulimit -f %d && exec %s %s",
+		effectiveLimits.MaxMemoryBytes/1024, // ulimit -v is in KiB
+		cpuSeconds,
+		effectiveLimits.MaxOpenFiles,
+		effectiveLimits.MaxFileSizeBytes/512, // ulimit -f is in 512-byte blocks
+		bin,
+		shellQuoteArgs(args),
+	)
+	return exec.CommandContext(ctx, "sh", "-c", script)
+}
+
+// shellQuoteArgs single-quotes each argument for safe interpolation into
+// the ulimit/exec shell script above.
+func shellQuoteArgs(args []string) string {
+	quoted := make([]string, len(args))
+	for i, a := range args {
+		quoted[i] = "'" + strings.ReplaceAll(a, "'", `'\''`) + "'"
+	}
+	return strings.Join(quoted, " ")
+}
+
+// cpuLimitKilled reports whether err is the *exec.ExitError produced when
+// the `ulimit -t` rlimit set up by rlimitedCommand reaps a runaway child
+// with SIGXCPU - the backstop cpuSecondsFor exists for, since killing the
+// process `sh` exec'd into via ctx's deadline doesn't reach a child that
+// process (e.g. `go run`) has already forked. Callers should treat this the same as a context-deadline
+// timeout rather than a generic runtime error.
+func cpuLimitKilled(err error) bool {
+	var exitErr *exec.ExitError
+	if !errors.As(err, &exitErr) {
+		return false
+	}
+	status, ok := exitErr.ProcessState.Sys().(syscall.WaitStatus)
+	if !ok {
+		return false
+	}
+	return status.Signaled() && status.Signal() == syscall.SIGXCPU
+}
@@ -0,0 +1,28 @@
+//go:build !linux
+
+package main
+
+import (
+	"context"
+	"os/exec"
+)
+
+// rlimitedGoCommand falls back to an unsandboxed (timeout-only) command on
+// platforms without POSIX rlimits.
+func rlimitedGoCommand(ctx context.Context, cpuSeconds int64, goArgs ...string) *exec.Cmd {
+	return rlimitedCommand(ctx, cpuSeconds, "go", goArgs...)
+}
+
+// rlimitedCommand falls back to an unsandboxed (timeout-only) command on
+// platforms without POSIX rlimits. cpuSeconds is unused here since there's
+// no rlimit to apply it to, but kept for signature parity with the Linux
+// build.
+func rlimitedCommand(ctx context.Context, cpuSeconds int64, bin string, args ...string) *exec.Cmd {
+	return exec.CommandContext(ctx, bin, args...)
+}
+
+// cpuLimitKilled always reports false here: without the rlimit sandbox
+// rlimitedCommand applies on Linux, there's no CPU-time kill to detect.
+func cpuLimitKilled(err error) bool {
+	return false
+}
@@ -0,0 +1,258 @@
+package main
+
+import (
+	"crypto/sha256"
+	"encoding/hex"
+	"encoding/json"
+	"fmt"
+	"io/ioutil"
+	"net/http"
+	"path/filepath"
+	"regexp"
+	"sort"
+	"strconv"
+	"strings"
+	"sync"
+	"time"
+
+	"github.com/gin-gonic/gin"
+)
+
+// Snippet is a named, reusable beforeSend/tracesSampler recipe paired with
+// a sample event to try it against - the unit the snippet library
+// catalogs and TransformRequest.SnippetID resolves.
+type Snippet struct {
+	ID          string                 `json:"id"`
+	Name        string                 `json:"name"`
+	Description string                 `json:"description,omitempty"`
+	SDK         string                 `json:"sdk"`
+	Code        string                 `json:"code"`
+	SampleEvent map[string]interface{} `json:"sampleEvent,omitempty"`
+}
+
+// SnippetStore is the storage interface the snippet endpoints are built
+// against, so operators can swap in-memory storage for a file-backed
+// library without touching the handlers.
+type SnippetStore interface {
+	List() []Snippet
+	Get(id string) (Snippet, bool)
+	Save(snip Snippet) error
+}
+
+// inMemorySnippetStore is the default store: a process-lifetime map, good
+// enough for a single-instance playground deployment.
+type inMemorySnippetStore struct {
+	mu       sync.RWMutex
+	snippets map[string]Snippet
+}
+
+func newInMemorySnippetStore() *inMemorySnippetStore {
+	return &inMemorySnippetStore{snippets: make(map[string]Snippet)}
+}
+
+func (s *inMemorySnippetStore) List() []Snippet {
+	s.mu.RLock()
+	defer s.mu.RUnlock()
+	out := make([]Snippet, 0, len(s.snippets))
+	for _, snip := range s.snippets {
+		out = append(out, snip)
+	}
+	sort.Slice(out, func(i, j int) bool { return out[i].ID < out[j].ID })
+	return out
+}
+
+func (s *inMemorySnippetStore) Get(id string) (Snippet, bool) {
+	s.mu.RLock()
+	defer s.mu.RUnlock()
+	snip, ok := s.snippets[id]
+	return snip, ok
+}
+
+func (s *inMemorySnippetStore) Save(snip Snippet) error {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	s.snippets[snip.ID] = snip
+	return nil
+}
+
+// fileSnippetStore wraps an inMemorySnippetStore with a directory of JSON
+// files as its backing: every *.json file in the directory is preloaded
+// into memory at startup, and every Save persists the snippet back to
+// <dir>/<id>.json so the library survives a restart.
+type fileSnippetStore struct {
+	*inMemorySnippetStore
+	dir string
+}
+
+// newFileSnippetStore preloads every *.json file in dir into memory.
+func newFileSnippetStore(dir string) (*fileSnippetStore, error) {
+	store := &fileSnippetStore{inMemorySnippetStore: newInMemorySnippetStore(), dir: dir}
+
+	entries, err := ioutil.ReadDir(dir)
+	if err != nil {
+		return nil, fmt.Errorf("failed to read snippet library dir: %w", err)
+	}
+
+	for _, entry := range entries {
+		if entry.IsDir() || !strings.HasSuffix(entry.Name(), ".json") {
+			continue
+		}
+		data, err := ioutil.ReadFile(filepath.Join(dir, entry.Name()))
+		if err != nil {
+			return nil, fmt.Errorf("failed to read snippet %s: %w", entry.Name(), err)
+		}
+		var snip Snippet
+		if err := json.Unmarshal(data, &snip); err != nil {
+			return nil, fmt.Errorf("failed to parse snippet %s: %w", entry.Name(), err)
+		}
+		if snip.ID == "" {
+			snip.ID = strings.TrimSuffix(entry.Name(), ".json")
+		}
+		store.inMemorySnippetStore.snippets[snip.ID] = snip
+	}
+
+	return store, nil
+}
+
+func (s *fileSnippetStore) Save(snip Snippet) error {
+	if err := s.inMemorySnippetStore.Save(snip); err != nil {
+		return err
+	}
+	data, err := json.MarshalIndent(snip, "", "  ")
+	if err != nil {
+		return err
+	}
+	return ioutil.WriteFile(filepath.Join(s.dir, snip.ID+".json"), data, 0644)
+}
+
+// snippetStore is the process-wide snippet library. main() upgrades it to
+// a fileSnippetStore when SNIPPET_LIBRARY_DIR is set.
+var snippetStore SnippetStore = newInMemorySnippetStore()
+
+var slugPattern = regexp.MustCompile(`[^a-z0-9]+`)
+
+// slugify turns a snippet name into a URL-safe id, e.g. "PII Scrubbing"
+// becomes "pii-scrubbing". newFileSnippetStore does not use it: a library
+// file without an explicit id takes its file name, minus ".json", as its id.
+func slugify(name string) string {
+	return strings.Trim(slugPattern.ReplaceAllString(strings.ToLower(name), "-"), "-")
+}
+
+// contentSnippetID derives a short, stable id from a snippet's sdk and
+// code, so two snippets saved with the same recipe - whatever they're
+// named - dedupe onto the same id and permalink instead of piling up
+// duplicates.
+func contentSnippetID(sdk, code string) string {
+	sum := sha256.Sum256([]byte(sdk + "\x00" + code))
+	return hex.EncodeToString(sum[:])[:10]
+}
+
+// defaultSnippetListLimit caps how many snippets listSnippetsHandler
+// returns per page when the caller doesn't specify one.
+const defaultSnippetListLimit = 20
+
+func listSnippetsHandler(c *gin.Context) {
+	all := snippetStore.List()
+
+	limit := defaultSnippetListLimit
+	if v := c.Query("limit"); v != "" {
+		if n, err := strconv.Atoi(v); err == nil && n > 0 {
+			limit = n
+		}
+	}
+	offset := 0
+	if v := c.Query("offset"); v != "" {
+		if n, err := strconv.Atoi(v); err == nil && n >= 0 {
+			offset = n
+		}
+	}
+
+	page := []Snippet{}
+	if offset < len(all) {
+		end := offset + limit
+		if end > len(all) {
+			end = len(all)
+		}
+		page = all[offset:end]
+	}
+
+	c.JSON(http.StatusOK, gin.H{
+		"snippets": page,
+		"total":    len(all),
+		"limit":    limit,
+		"offset":   offset,
+	})
+}
+
+func getSnippetHandler(c *gin.Context) {
+	snip, ok := snippetStore.Get(c.Param("id"))
+	if !ok {
+		c.JSON(http.StatusNotFound, gin.H{"error": fmt.Sprintf("snippet %q not found", c.Param("id"))})
+		return
+	}
+	c.JSON(http.StatusOK, snip)
+}
+
+func createSnippetHandler(c *gin.Context) {
+	var snip Snippet
+	if err := c.ShouldBindJSON(&snip); err != nil || snip.Name == "" || snip.Code == "" {
+		c.JSON(http.StatusBadRequest, gin.H{"error": "snippet requires at least name and code"})
+		return
+	}
+	if snip.SDK == "" {
+		snip.SDK = "go"
+	}
+	if snip.ID == "" {
+		snip.ID = contentSnippetID(snip.SDK, snip.Code)
+	}
+
+	if err := snippetStore.Save(snip); err != nil {
+		c.JSON(http.StatusInternalServerError, gin.H{"error": err.Error()})
+		return
+	}
+	c.JSON(http.StatusCreated, gin.H{"id": snip.ID, "url": "/s/" + snip.ID})
+}
+
+// SnippetRunRequest optionally overrides the event a snippet runs against;
+// an empty body falls back to the snippet's own SampleEvent.
+type SnippetRunRequest struct {
+	Event map[string]interface{} `json:"event,omitempty"`
+}
+
+// runSnippetHandler executes a saved snippet against either a caller-
+// supplied event or its own SampleEvent, sharing respondWithTransform
+// with /transform so a snippet behaves identically run either way.
+func runSnippetHandler(c *gin.Context) {
+	snip, ok := snippetStore.Get(c.Param("id"))
+	if !ok {
+		c.JSON(http.StatusNotFound, gin.H{"error": fmt.Sprintf("snippet %q not found", c.Param("id"))})
+		return
+	}
+
+	var req SnippetRunRequest
+	// A missing or empty body just means "use the snippet's own sample
+	// event"; only a malformed one is an error.
+	if c.Request.ContentLength != 0 {
+		if err := c.ShouldBindJSON(&req); err != nil {
+			c.JSON(http.StatusBadRequest, gin.H{"error": "invalid request body"})
+			return
+		}
+	}
+
+	event := req.Event
+	if event == nil {
+		event = snip.SampleEvent
+	}
+	if event == nil {
+		c.JSON(http.StatusBadRequest, gin.H{"error": "snippet has no sampleEvent and no event was supplied"})
+		return
+	}
+
+	sdk, executor, ok := executorFor(c, snip.SDK)
+	if !ok {
+		c.JSON(http.StatusBadRequest, gin.H{"error": fmt.Sprintf("unsupported sdk %q", snip.SDK)})
+		return
+	}
+
+	respondWithTransform(c, sdk, executor, event, snip.Code, &snip, time.Now())
+}
@@ -0,0 +1,115 @@
+//go:build sqlite
+
+package main
+
+import (
+	"database/sql"
+	"encoding/json"
+	"fmt"
+	"log"
+	"os"
+
+	_ "modernc.org/sqlite"
+)
+
+// sqliteSnippetStore persists snippets to a SQLite database file instead
+// of in-memory maps or loose JSON files - real persistence for operators
+// who want the library to survive a restart without managing a directory
+// of files. Only compiled in with `-tags sqlite`, since this repo
+// otherwise has no database dependency at all.
+type sqliteSnippetStore struct {
+	db *sql.DB
+}
+
+// newSQLiteSnippetStore opens (creating if needed) a SQLite database at
+// path and ensures its schema exists.
+func newSQLiteSnippetStore(path string) (*sqliteSnippetStore, error) {
+	db, err := sql.Open("sqlite", path)
+	if err != nil {
+		return nil, fmt.Errorf("failed to open sqlite snippet store: %w", err)
+	}
+	if _, err := db.Exec(`CREATE TABLE IF NOT EXISTS snippets (
+		id TEXT PRIMARY KEY,
+		name TEXT NOT NULL,
+		description TEXT,
+		sdk TEXT NOT NULL,
+		code TEXT NOT NULL,
+		sample_event TEXT
+	)`); err != nil {
+		return nil, fmt.Errorf("failed to create snippets table: %w", err)
+	}
+	return &sqliteSnippetStore{db: db}, nil
+}
+
+func (s *sqliteSnippetStore) List() []Snippet {
+	rows, err := s.db.Query(`SELECT id, name, description, sdk, code, sample_event FROM snippets ORDER BY id`)
+	if err != nil {
+		return nil
+	}
+	defer rows.Close()
+
+	var snippets []Snippet
+	for rows.Next() {
+		snip, err := scanSnippet(rows)
+		if err != nil {
+			continue
+		}
+		snippets = append(snippets, snip)
+	}
+	return snippets
+}
+
+func (s *sqliteSnippetStore) Get(id string) (Snippet, bool) {
+	snip, err := scanSnippet(s.db.QueryRow(`SELECT id, name, description, sdk, code, sample_event FROM snippets WHERE id = ?`, id))
+	if err != nil {
+		return Snippet{}, false
+	}
+	return snip, true
+}
+
+func (s *sqliteSnippetStore) Save(snip Snippet) error {
+	sampleEvent, err := json.Marshal(snip.SampleEvent)
+	if err != nil {
+		return fmt.Errorf("failed to marshal sampleEvent: %w", err)
+	}
+	_, err = s.db.Exec(`INSERT INTO snippets (id, name, description, sdk, code, sample_event)
+		VALUES (?, ?, ?, ?, ?, ?)
+		ON CONFLICT(id) DO UPDATE SET name=excluded.name, description=excluded.description,
+			sdk=excluded.sdk, code=excluded.code, sample_event=excluded.sample_event`,
+		snip.ID, snip.Name, snip.Description, snip.SDK, snip.Code, string(sampleEvent))
+	return err
+}
+
+// rowScanner is satisfied by both *sql.Row and *sql.Rows, letting
+// scanSnippet back both Get and List without duplicating the column list.
+type rowScanner interface {
+	Scan(dest ...interface{}) error
+}
+
+func scanSnippet(row rowScanner) (Snippet, error) {
+	var snip Snippet
+	var description, sampleEvent sql.NullString
+	if err := row.Scan(&snip.ID, &snip.Name, &description, &snip.SDK, &snip.Code, &sampleEvent); err != nil {
+		return Snippet{}, err
+	}
+	snip.Description = description.String
+	if sampleEvent.Valid && sampleEvent.String != "" && sampleEvent.String != "null" {
+		json.Unmarshal([]byte(sampleEvent.String), &snip.SampleEvent)
+	}
+	return snip, nil
+}
+
+// SNIPPET_SQLITE_PATH lets operators back the snippet library with a
+// SQLite database instead of the in-memory default, without main() ever
+// needing to know the sqlite build tag is active.
+func init() {
+	path := os.Getenv("SNIPPET_SQLITE_PATH")
+	if path == "" {
+		return
+	}
+	store, err := newSQLiteSnippetStore(path)
+	if err != nil {
+		log.Fatalf("failed to open sqlite snippet store at %s: %v", path, err)
+	}
+	snippetStore = store
+}
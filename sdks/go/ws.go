@@ -0,0 +1,118 @@
+package main
+
+import (
+	"crypto/sha256"
+	"encoding/hex"
+	"log"
+	"net/http"
+	"sync"
+	"time"
+
+	"github.com/gin-gonic/gin"
+	"github.com/gorilla/websocket"
+)
+
+// wsDebounce is how long the session waits after the last edit before
+// actually validating/transforming, so a burst of keystrokes only pays the
+// evaluation cost once.
+const wsDebounce = 250 * time.Millisecond
+
+var wsUpgrader = websocket.Upgrader{
+	ReadBufferSize:  4096,
+	WriteBufferSize: 4096,
+	// This endpoint carries no credentials and is meant to be embedded by
+	// the playground UI from any origin in dev, so a permissive check is
+	// fine here.
+	CheckOrigin: func(r *http.Request) bool { return true },
+}
+
+// wsMessage is what the client pushes on every edit: the current
+// beforeSend/tracesSampler snippet and the event to try it against.
+type wsMessage struct {
+	BeforeSendCode string                 `json:"beforeSendCode"`
+	Event          map[string]interface{} `json:"event"`
+}
+
+// wsFrame is the structured envelope streamed back to the client. Type is
+// one of "validation", "compile_progress", "stdout_chunk", "result", or
+// "error".
+type wsFrame struct {
+	Type  string      `json:"type"`
+	Data  interface{} `json:"data,omitempty"`
+	Error string      `json:"error,omitempty"`
+}
+
+// validationCache memoizes validateCode results keyed by a hash of
+// beforeSendCode, so repeated keystrokes that resubmit identical code
+// skip recompilation entirely.
+var validationCache sync.Map // map[string]ValidationResponse
+
+func codeHash(code string) string {
+	sum := sha256.Sum256([]byte(code))
+	return hex.EncodeToString(sum[:])
+}
+
+// wsHandler keeps a session open so the playground UI can push code edits
+// and receive incremental validate/transform results without paying the
+// go-toolchain startup cost per keystroke. Validation results are cached
+// by code hash; transforms run through the in-process interp engine with
+// no subprocess or temp file. Validating code not yet in the cache still
+// runs a go build in a temp directory.
+func wsHandler(c *gin.Context) {
+	conn, err := wsUpgrader.Upgrade(c.Writer, c.Request, nil)
+	if err != nil {
+		return
+	}
+	defer conn.Close()
+
+	var (
+		mu    sync.Mutex
+		timer *time.Timer
+	)
+
+	send := func(frame wsFrame) {
+		mu.Lock()
+		defer mu.Unlock()
+		if err := conn.WriteJSON(frame); err != nil {
+			log.Printf("ws: write failed: %v", err)
+		}
+	}
+
+	evaluate := func(msg wsMessage) {
+		hash := codeHash(msg.BeforeSendCode)
+
+		validation, ok := validationCache.Load(hash)
+		if !ok {
+			validation = validateCode(msg.BeforeSendCode)
+			validationCache.Store(hash, validation)
+		}
+		send(wsFrame{Type: "validation", Data: validation})
+
+		if !validation.(ValidationResponse).Valid {
+			return
+		}
+
+		send(wsFrame{Type: "compile_progress", Data: "running"})
+
+		result, err := runInterp(msg.Event, msg.BeforeSendCode)
+		if err != nil {
+			send(wsFrame{Type: "error", Error: err.Error()})
+			return
+		}
+		send(wsFrame{Type: "result", Data: result})
+	}
+
+	for {
+		var msg wsMessage
+		if err := conn.ReadJSON(&msg); err != nil {
+			return
+		}
+
+		mu.Lock()
+		if timer != nil {
+			timer.Stop()
+		}
+		timer = time.AfterFunc(wsDebounce, func() { evaluate(msg) })
+		mu.Unlock()
+	}
+}